@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -18,18 +19,25 @@ import (
 
 	"qr-saas/internal/admin"
 	"qr-saas/internal/analytics"
+	"qr-saas/internal/assets"
 	"qr-saas/internal/audit"
 	"qr-saas/internal/auth"
+	"qr-saas/internal/batch"
 	"qr-saas/internal/billing"
 	"qr-saas/internal/config"
 	"qr-saas/internal/db"
+	"qr-saas/internal/export"
 	internalhttp "qr-saas/internal/http"
 	"qr-saas/internal/http/middleware"
 	"qr-saas/internal/projects"
 	"qr-saas/internal/qr"
+	"qr-saas/internal/qrtypes"
 	"qr-saas/internal/redirect"
+	"qr-saas/internal/rules"
+	"qr-saas/internal/scenes"
 	"qr-saas/internal/settings"
 	"qr-saas/internal/templates"
+	"qr-saas/internal/variants"
 )
 
 func main() {
@@ -62,28 +70,60 @@ func main() {
 	googleOAuth := auth.NewGoogleOAuth(cfg)
 	authSvc := auth.NewService(authRepo, googleOAuth, cfg.JWTSecret)
 
+	// Assets (uploaded logos, local disk or S3)
+	assetStore, err := assets.NewStore(cfg)
+	if err != nil {
+		log.Fatal("Asset store error:", err)
+	}
+	assetsRepo := assets.NewRepository(pgDB)
+	assetsSvc := assets.NewService(assetsRepo, assetStore)
+
+	// Scenes (mockup backgrounds for composited QR images)
+	scenesRepo := scenes.NewRepository(pgDB)
+	scenesSvc := scenes.NewService(scenesRepo, cfg.ScenesDir)
+
 	// QR
 	qrRepo := qr.NewRepository(pgDB)
-	qrSvc := qr.NewService(qrRepo, cfg.BaseURL)
+	qrSvc := qr.NewService(qrRepo, cfg.BaseURL, scenesSvc, assetsSvc, qr.NewRedisImageCache(redisClient, 24*time.Hour))
 
 	// Analytics
 	analyticsRepo := analytics.NewRepository(pgDB)
 	analyticsSvc := analytics.NewService(analyticsRepo)
 
+	// Typed code data (app store links are read when redirecting)
+	qrTypesRepo := qrtypes.NewRepository(pgDB)
+
+	// Settings
+	settingsRepo := settings.NewRepository(pgDB)
+	settingsSvc := settings.NewService(settingsRepo)
+
+	// Redirect rules (conditional destinations of dynamic codes)
+	rulesRepo := rules.NewRepository(pgDB)
+	rulesSvc := rules.NewService(rulesRepo, qrRepo, settingsSvc)
+
+	// A/B tests (weighted destinations of dynamic codes)
+	variantsRepo := variants.NewRepository(pgDB)
+	variantsSvc := variants.NewService(variantsRepo, qrRepo)
+
 	// Redirect
-	redirectSvc := redirect.NewService(qrRepo, analyticsSvc)
+	redirectSvc := redirect.NewService(qrRepo, qrTypesRepo, rulesRepo, variantsRepo, settingsRepo, analyticsSvc, redisClient, cfg.JWTSecret)
 
 	// Projects
 	projectsRepo := projects.NewRepository(pgDB)
 	projectsSvc := projects.NewService(projectsRepo, qrRepo)
 
-	// Settings
-	settingsRepo := settings.NewRepository(pgDB)
-	settingsSvc := settings.NewService(settingsRepo)
+	// Bulk export (ZIP archives, big ones built in the background)
+	exportSvc := export.NewService(qrSvc, projectsSvc, scenesSvc, assetStore, redisClient)
 
 	// Templates
 	templatesRepo := templates.NewRepository(pgDB)
-	templatesSvc := templates.NewService(templatesRepo)
+	templatesSvc := templates.NewService(templatesRepo, assetsSvc)
+
+	// Typed codes (WiFi, vCard), dynamic ones get a templates landing page
+	qrTypesSvc := qrtypes.NewService(qrTypesRepo, qrRepo, rulesRepo, variantsRepo, templatesSvc, cfg.BaseURL)
+
+	// Batch import (one code per CSV row)
+	batchSvc := batch.NewService(qrSvc, qrTypesSvc, projectsSvc, exportSvc)
 
 	// Billing
 	billingRepo := billing.NewRepository(pgDB)
@@ -112,6 +152,41 @@ func main() {
 	apiQR.Use(middleware.JWTAuth(authSvc))
 	qr.RegisterRoutes(apiQR, qrSvc)
 
+	// QR EXPORT
+	apiExport := r.Group("/api/qr/export")
+	apiExport.Use(middleware.JWTAuth(authSvc))
+	export.RegisterRoutes(apiExport, exportSvc)
+
+	// QR TYPES
+	apiQRTypes := r.Group("/api/qr/types")
+	apiQRTypes.Use(middleware.JWTAuth(authSvc))
+	qrtypes.RegisterRoutes(apiQRTypes, qrTypesSvc)
+
+	// REDIRECT RULES
+	apiRules := r.Group("/api/qr/rules")
+	apiRules.Use(middleware.JWTAuth(authSvc))
+	rules.RegisterRoutes(apiRules, rulesSvc)
+
+	// A/B VARIANTS
+	apiVariants := r.Group("/api/qr/variants")
+	apiVariants.Use(middleware.JWTAuth(authSvc))
+	variants.RegisterRoutes(apiVariants, variantsSvc)
+
+	// QR BATCH
+	apiBatch := r.Group("/api/qr/batch")
+	apiBatch.Use(middleware.JWTAuth(authSvc))
+	batch.RegisterRoutes(apiBatch, batchSvc)
+
+	// ASSETS
+	apiAssets := r.Group("/api/assets")
+	apiAssets.Use(middleware.JWTAuth(authSvc))
+	assets.RegisterRoutes(apiAssets, assetsSvc)
+
+	// SCENES
+	apiScenes := r.Group("/api/scenes")
+	apiScenes.Use(middleware.JWTAuth(authSvc))
+	scenes.RegisterRoutes(apiScenes, scenesSvc)
+
 	// ANALYTICS
 	apiAnalytics := r.Group("/api/analytics")
 	apiAnalytics.Use(middleware.JWTAuth(authSvc))
@@ -150,6 +225,9 @@ func main() {
 	// REDIRECT (public)
 	redirect.RegisterRoutes(r, redirectSvc)
 
+	// LANDING PAGES (public)
+	templates.RegisterPublicRoutes(r, templatesSvc)
+
 	// --------------------------
 	// SWAGGER DOCS
 	// --------------------------
@@ -0,0 +1,85 @@
+// migrate-logos moves base64 logos embedded in qr_codes.design_json and
+// templates.design_json into the asset store, replacing them with a
+// logoAssetId. Safe to re-run: rows without an inline logo are skipped
+// and identical images dedupe to the same asset.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"qr-saas/internal/assets"
+	"qr-saas/internal/config"
+	"qr-saas/internal/db"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	cfg := config.Load()
+	ctx := context.Background()
+
+	pgDB := db.NewPostgresPool(cfg)
+	defer pgDB.Close()
+
+	store, err := assets.NewStore(cfg)
+	if err != nil {
+		log.Fatal("Asset store error:", err)
+	}
+	assetsSvc := assets.NewService(assets.NewRepository(pgDB), store)
+
+	for _, table := range []string{"qr_codes", "templates"} {
+		moved, failed := migrateTable(ctx, pgDB, assetsSvc, table)
+		fmt.Printf("✅ %s: %d logos moved, %d failed\n", table, moved, failed)
+	}
+}
+
+func migrateTable(ctx context.Context, pg *pgxpool.Pool, svc assets.Service, table string) (moved, failed int) {
+	// Global templates have no owner; their logos can't become user assets
+	rows, err := pg.Query(ctx, fmt.Sprintf(
+		`SELECT id, user_id, design_json::text FROM %s
+         WHERE user_id IS NOT NULL AND design_json::text LIKE '%%"logo":%%'`, table))
+	if err != nil {
+		log.Fatalf("❌ Query %s: %v", table, err)
+	}
+
+	type row struct {
+		id, userID, design string
+	}
+	var pending []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.userID, &r.design); err != nil {
+			log.Fatalf("❌ Scan %s: %v", table, err)
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+
+	for _, r := range pending {
+		var design map[string]interface{}
+		if err := json.Unmarshal([]byte(r.design), &design); err != nil || design == nil {
+			continue
+		}
+		if logo, _ := design["logo"].(string); logo == "" {
+			continue
+		}
+
+		if err := svc.ExtractInlineLogo(ctx, r.userID, design); err != nil {
+			fmt.Printf("⚠️ %s %s: %v\n", table, r.id, err)
+			failed++
+			continue
+		}
+		out, _ := json.Marshal(design)
+
+		if _, err := pg.Exec(ctx, fmt.Sprintf(`UPDATE %s SET design_json=$1 WHERE id=$2`, table), string(out), r.id); err != nil {
+			fmt.Printf("⚠️ %s %s: %v\n", table, r.id, err)
+			failed++
+			continue
+		}
+		moved++
+	}
+	return moved, failed
+}
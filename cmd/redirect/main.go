@@ -9,31 +9,41 @@ import (
 	"qr-saas/internal/config"
 	"qr-saas/internal/db"
 	"qr-saas/internal/qr"
+	"qr-saas/internal/qrtypes"
 	"qr-saas/internal/redirect"
+	"qr-saas/internal/rules"
+	"qr-saas/internal/settings"
+	"qr-saas/internal/variants"
 )
 
 func main() {
 	cfg := config.Load()
 
-	// ClickHouse for analytics (OK)
-	chConn, err := db.NewClickHouse(cfg)
-	if err != nil {
-		log.Fatal("❌ ClickHouse:", err)
-	}
-
-	// Postgres for QR metadata (IMPORTANT)
+	// Postgres for QR metadata and scan analytics
 	pgDB := db.NewPostgresPool(cfg)
 
+	// Redis for failed password attempts
+	redisClient := db.NewRedis(cfg.RedisURL)
+
 	// Repositories
 	qrRepo := qr.NewRepository(pgDB)
-	analyticsRepo := analytics.NewRepository(chConn)
+	qrTypesRepo := qrtypes.NewRepository(pgDB)
+	rulesRepo := rules.NewRepository(pgDB)
+	variantsRepo := variants.NewRepository(pgDB)
+	settingsRepo := settings.NewRepository(pgDB)
+	analyticsRepo := analytics.NewRepository(pgDB)
 
 	// Services
 	analyticsSvc := analytics.NewService(analyticsRepo)
-	redirectSvc := redirect.NewService(qrRepo, analyticsSvc)
+	redirectSvc := redirect.NewService(qrRepo, qrTypesRepo, rulesRepo, variantsRepo, settingsRepo, analyticsSvc, redisClient, cfg.JWTSecret)
 
 	// Router
 	r := gin.Default()
+	// Client IPs key the password attempt limits, only proxies we run
+	// may set them
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("❌ TRUSTED_PROXIES:", err)
+	}
 	redirect.RegisterRoutes(r, redirectSvc)
 
 	log.Println("🚀 Redirect service running on :8081")
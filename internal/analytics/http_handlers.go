@@ -22,6 +22,9 @@ func RegisterRoutes(r *gin.RouterGroup, svc Service) {
 	// GET /api/analytics/:qrID/timeseries?from=...&to=...&granularity=day
 	r.GET("/:qrID/timeseries", h.GetTimeSeries)
 
+	// GET /api/analytics/:qrID/variants?from=...&to=...
+	r.GET("/:qrID/variants", h.GetVariantStats)
+
 	r.GET("/dashboard", h.GetDashboardStats)
 
 	r.GET("/dashboard/timeseries", h.GetGlobalTimeSeries)
@@ -136,6 +139,32 @@ func (h *Handler) GetTimeSeries(c *gin.Context) {
 	c.JSON(http.StatusOK, points)
 }
 
+// GetVariantStats godoc
+// @Summary Get A/B test results of a QR code
+// @Description Returns scans, unique scanners and conversions per variant
+// @Tags Analytics
+// @Produce json
+// @Param qrID path string true "QR Code ID"
+// @Param from query string false "From Date YYYY-MM-DD"
+// @Param to query string false "To Date YYYY-MM-DD"
+// @Security BearerAuth
+// @Success 200 {array} VariantStats
+// @Router /api/analytics/{qrID}/variants [get]
+func (h *Handler) GetVariantStats(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+		return
+	}
+
+	stats, err := h.svc.GetVariantStats(c.Request.Context(), c.GetString("user_id"), c.Param("qrID"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
 // Handler
 func (h *Handler) GetDashboardStats(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -22,6 +22,26 @@ type ScanEvent struct {
 	OS         string
 	Browser    string
 	Referer    string
+	Variant    string // A/B variant the scan was sent to, empty without a test
+}
+
+// ConversionEvent is a landing page reporting a scanner converted
+type ConversionEvent struct {
+	EventID     string
+	QRID        string
+	UserID      string
+	Variant     string
+	Visitor     string // hash of IP and user agent
+	ConvertedAt time.Time
+}
+
+// VariantStats are the scans and conversions of one A/B variant
+type VariantStats struct {
+	Variant        string  `json:"variant"`
+	Scans          int64   `json:"scans"`
+	UniqueScanners int64   `json:"unique_scanners"`
+	Conversions    int64   `json:"conversions"`     // once per visitor
+	ConversionRate float64 `json:"conversion_rate"` // conversions per unique scanner
 }
 
 // Summary Struct (Same as before)
@@ -44,6 +64,8 @@ type Repository interface {
 	GetGlobalStats(ctx context.Context, userID string) (*Summary, error)
 	GetTimeSeries(ctx context.Context, userID, qrID string, from, to time.Time, granularity string) ([]TimePoint, error)
 	GetGlobalTimeSeries(ctx context.Context, userID string, from, to time.Time, granularity string) ([]TimePoint, error)
+	InsertConversion(ctx context.Context, ev ConversionEvent) error
+	GetVariantStats(ctx context.Context, userID, qrID string, from, to time.Time) ([]VariantStats, error)
 }
 
 type repository struct {
@@ -62,13 +84,15 @@ func (r *repository) InsertScanEvent(ctx context.Context, ev ScanEvent) error {
 		INSERT INTO scan_events (
 			id, qr_id, user_id, scanned_at, 
 			ip, country, city, user_agent, 
-			device_type, os, browser, referer
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			device_type, os, browser, referer,
+			variant
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	_, err := r.db.Exec(ctx, query,
 		ev.EventID, ev.QRID, ev.UserID, ev.ScannedAt,
 		ev.IP, ev.Country, ev.City, ev.UserAgent,
 		ev.DeviceType, ev.OS, ev.Browser, ev.Referer,
+		ev.Variant,
 	)
 	return err
 }
@@ -207,3 +231,51 @@ func (r *repository) fetchTimeSeries(ctx context.Context, userID, qrID string, f
 	}
 	return points, nil
 }
+
+// ---------------------------------------------------------
+// 5. A/B VARIANTS
+// ---------------------------------------------------------
+func (r *repository) InsertConversion(ctx context.Context, ev ConversionEvent) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO conversion_events (id, qr_id, user_id, variant, visitor, converted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, ev.EventID, ev.QRID, ev.UserID, ev.Variant, ev.Visitor, ev.ConvertedAt)
+	return err
+}
+
+func (r *repository) GetVariantStats(ctx context.Context, userID, qrID string, from, to time.Time) ([]VariantStats, error) {
+	query := `
+		WITH scans AS (
+			SELECT variant, count(*) AS scans, count(DISTINCT (ip, user_agent)) AS scanners
+			FROM scan_events
+			WHERE user_id = $1 AND qr_id = $2 AND scanned_at BETWEEN $3 AND $4
+			GROUP BY variant
+		), conversions AS (
+			SELECT variant, count(DISTINCT visitor) AS conversions
+			FROM conversion_events
+			WHERE user_id = $1 AND qr_id = $2 AND converted_at BETWEEN $3 AND $4
+			GROUP BY variant
+		)
+		SELECT COALESCE(s.variant, c.variant), COALESCE(s.scans, 0), COALESCE(s.scanners, 0), COALESCE(c.conversions, 0)
+		FROM scans s FULL OUTER JOIN conversions c ON s.variant = c.variant
+		ORDER BY 1
+	`
+	rows, err := r.db.Query(ctx, query, userID, qrID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []VariantStats{}
+	for rows.Next() {
+		var v VariantStats
+		if err := rows.Scan(&v.Variant, &v.Scans, &v.UniqueScanners, &v.Conversions); err != nil {
+			return nil, err
+		}
+		if v.UniqueScanners > 0 {
+			v.ConversionRate = float64(v.Conversions) / float64(v.UniqueScanners)
+		}
+		stats = append(stats, v)
+	}
+	return stats, rows.Err()
+}
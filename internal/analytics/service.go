@@ -12,6 +12,10 @@ type Service interface {
 	GetTimeSeries(ctx context.Context, userID, qrID string, from, to time.Time, granularity string) ([]TimePoint, error)
 	GetGlobalStats(ctx context.Context, userID string) (*Summary, error)
 	GetGlobalTimeSeries(ctx context.Context, userID string, from, to time.Time, granularity string) ([]TimePoint, error)
+	InsertConversion(ctx context.Context, ev ConversionEvent) error
+	// GetVariantStats reports scans and conversions per A/B variant;
+	// scans outside a test have the variant ""
+	GetVariantStats(ctx context.Context, userID, qrID string, from, to time.Time) ([]VariantStats, error)
 }
 
 type service struct {
@@ -49,3 +53,11 @@ func (s *service) GetGlobalTimeSeries(ctx context.Context, userID string, from,
 	}
 	return s.repo.GetGlobalTimeSeries(ctx, userID, from, to, granularity)
 }
+
+func (s *service) InsertConversion(ctx context.Context, ev ConversionEvent) error {
+	return s.repo.InsertConversion(ctx, ev)
+}
+
+func (s *service) GetVariantStats(ctx context.Context, userID, qrID string, from, to time.Time) ([]VariantStats, error) {
+	return s.repo.GetVariantStats(ctx, userID, qrID, from, to)
+}
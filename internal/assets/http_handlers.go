@@ -0,0 +1,152 @@
+package assets
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	svc Service
+}
+
+func RegisterRoutes(r *gin.RouterGroup, svc Service) {
+	h := &Handler{svc}
+
+	r.POST("/", h.Upload)
+	r.GET("/", h.List)
+	r.GET("/:id", h.GetOne)
+	r.GET("/:id/content", h.Content)
+	r.DELETE("/:id", h.Delete)
+}
+
+// @Summary Upload an asset (logo)
+// @Description PNG, JPEG, GIF or WebP, up to 2 MB and 4096px per side. Reference it from a design as logoAssetId.
+// @Tags Assets
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "image"
+// @Success 201 {object} Asset
+// @Router /api/assets/ [post]
+func (h *Handler) Upload(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable upload"})
+		return
+	}
+	defer f.Close()
+
+	a, err := h.svc.Upload(c.Request.Context(), userID, fh.Filename, f)
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrUnsupportedType) || errors.Is(err, ErrTooManyPixels) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, a)
+}
+
+// @Summary List my assets
+// @Tags Assets
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} Asset
+// @Router /api/assets/ [get]
+func (h *Handler) List(c *gin.Context) {
+	out, err := h.svc.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// @Summary Get asset metadata
+// @Tags Assets
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Success 200 {object} Asset
+// @Router /api/assets/{id} [get]
+func (h *Handler) GetOne(c *gin.Context) {
+	a, err := h.svc.Get(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// @Summary Download asset content
+// @Tags Assets
+// @Security BearerAuth
+// @Produce png
+// @Param id path string true "Asset ID"
+// @Router /api/assets/{id}/content [get]
+func (h *Handler) Content(c *gin.Context) {
+	a, rc, err := h.svc.Open(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrBlobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+		return
+	}
+	defer rc.Close()
+
+	// Content addressed: the bytes behind an ID never change
+	c.Header("Content-Type", a.MIME)
+	c.Header("Content-Length", strconv.FormatInt(a.Size, 10))
+	c.Header("ETag", `"`+a.SHA256+`"`)
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, rc)
+}
+
+// @Summary Delete asset
+// @Tags Assets
+// @Security BearerAuth
+// @Param id path string true "Asset ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "still the logo of a code or template"
+// @Router /api/assets/{id} [delete]
+func (h *Handler) Delete(c *gin.Context) {
+	err := h.svc.Delete(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,22 @@
+package assets
+
+import "time"
+
+// Asset is an uploaded image (logos for now). The bytes live in the
+// content-addressed Store under Key; the row only holds metadata.
+type Asset struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Filename  string    `json:"filename"`
+	MIME      string    `json:"mime"`
+	Size      int64     `json:"size"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Key is where the asset's bytes are stored
+func (a *Asset) Key() string {
+	return contentKey(a.SHA256)
+}
@@ -0,0 +1,110 @@
+package assets
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	Create(ctx context.Context, a *Asset) error
+	GetByID(ctx context.Context, id, userID string) (*Asset, error)
+	GetByHash(ctx context.Context, userID, sum string) (*Asset, error)
+	ListByUser(ctx context.Context, userID string) ([]Asset, error)
+	// InUse reports whether a code or template of the user still has the
+	// asset as its logo
+	InUse(ctx context.Context, id, userID string) (bool, error)
+	// Delete returns ErrNotFound when the user has no such asset
+	Delete(ctx context.Context, id, userID string) error
+}
+
+type repository struct {
+	pg *pgxpool.Pool
+}
+
+func NewRepository(pg *pgxpool.Pool) Repository {
+	return &repository{pg}
+}
+
+const assetColumns = `id, user_id, filename, mime, size, width, height, sha256, created_at`
+
+func scanAsset(row pgx.Row) (*Asset, error) {
+	var a Asset
+	err := row.Scan(&a.ID, &a.UserID, &a.Filename, &a.MIME, &a.Size, &a.Width, &a.Height, &a.SHA256, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *repository) Create(ctx context.Context, a *Asset) error {
+	_, err := r.pg.Exec(ctx,
+		`INSERT INTO assets (`+assetColumns+`)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		a.ID, a.UserID, a.Filename, a.MIME, a.Size, a.Width, a.Height, a.SHA256, a.CreatedAt,
+	)
+	return err
+}
+
+func (r *repository) GetByID(ctx context.Context, id, userID string) (*Asset, error) {
+	row := r.pg.QueryRow(ctx,
+		`SELECT `+assetColumns+` FROM assets WHERE id=$1 AND user_id=$2`,
+		id, userID)
+	return scanAsset(row)
+}
+
+func (r *repository) GetByHash(ctx context.Context, userID, sum string) (*Asset, error) {
+	row := r.pg.QueryRow(ctx,
+		`SELECT `+assetColumns+` FROM assets WHERE user_id=$1 AND sha256=$2 LIMIT 1`,
+		userID, sum)
+	return scanAsset(row)
+}
+
+func (r *repository) ListByUser(ctx context.Context, userID string) ([]Asset, error) {
+	rows, err := r.pg.Query(ctx,
+		`SELECT `+assetColumns+` FROM assets WHERE user_id=$1 ORDER BY created_at DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Asset{}
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, rows.Err()
+}
+
+// InUse looks for the ID in the stored designs; asset IDs are UUIDs, so
+// a match is the design's logoAssetId
+func (r *repository) InUse(ctx context.Context, id, userID string) (bool, error) {
+	var used bool
+	err := r.pg.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM qr_codes WHERE user_id=$2 AND strpos(design_json::text, $1) > 0)
+             OR EXISTS (SELECT 1 FROM templates WHERE user_id=$2 AND strpos(design_json::text, $1) > 0)`,
+		id, userID).Scan(&used)
+	return used, err
+}
+
+func (r *repository) Delete(ctx context.Context, id, userID string) error {
+	cmd, err := r.pg.Exec(ctx,
+		`DELETE FROM assets WHERE id=$1 AND user_id=$2`,
+		id, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "golang.org/x/image/webp"
+)
+
+// Upload limits. Logos are drawn at a fifth of the code, so anything
+// bigger than this only costs storage and render time.
+const (
+	MaxUploadSize = 2 << 20 // bytes
+	MaxDimension  = 4096    // px, either side
+)
+
+// allowedMIME are the sniffed types we accept, all decodable by the renderer
+var allowedMIME = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var (
+	ErrNotFound        = errors.New("asset not found")
+	ErrTooLarge        = fmt.Errorf("asset exceeds %d bytes", MaxUploadSize)
+	ErrUnsupportedType = errors.New("asset must be a png, jpeg, gif or webp image")
+	ErrTooManyPixels   = fmt.Errorf("image sides must be at most %d px", MaxDimension)
+	ErrInUse           = errors.New("asset is the logo of a qr code or template, change their designs first")
+)
+
+type Service interface {
+	Upload(ctx context.Context, userID, filename string, r io.Reader) (*Asset, error)
+	ImportInline(ctx context.Context, userID, data string) (*Asset, error)
+	ExtractInlineLogo(ctx context.Context, userID string, design map[string]interface{}) error
+	Get(ctx context.Context, userID, id string) (*Asset, error)
+	Open(ctx context.Context, userID, id string) (*Asset, io.ReadCloser, error)
+	LoadImage(ctx context.Context, userID, id string) (image.Image, error)
+	List(ctx context.Context, userID string) ([]Asset, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type service struct {
+	repo  Repository
+	store Store
+}
+
+func NewService(repo Repository, store Store) Service {
+	return &service{repo: repo, store: store}
+}
+
+// Upload validates and stores an image. The same bytes uploaded twice
+// by one user return the existing asset instead of a copy.
+func (s *service) Upload(ctx context.Context, userID, filename string, r io.Reader) (*Asset, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUploadSize {
+		return nil, ErrTooLarge
+	}
+
+	// Trust the bytes, not the client's Content-Type
+	mime := http.DetectContentType(data)
+	if !allowedMIME[mime] {
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, ErrTooManyPixels
+	}
+
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+
+	existing, err := s.repo.GetByHash(ctx, userID, sum)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	a := &Asset{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Filename:  filename,
+		MIME:      mime,
+		Size:      int64(len(data)),
+		Width:     cfg.Width,
+		Height:    cfg.Height,
+		SHA256:    sum,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	// Another user may already have stored the same blob
+	ok, err := s.store.Exists(ctx, a.Key())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.store.Put(ctx, a.Key(), bytes.NewReader(data), a.Size, mime); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ImportInline stores a base64 image, with or without a data URI
+// prefix, as the legacy design_json "logo" field holds them.
+func (s *service) ImportInline(ctx context.Context, userID, data string) (*Asset, error) {
+	parts := strings.Split(data, ",")
+	decoded, err := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	return s.Upload(ctx, userID, "logo", bytes.NewReader(decoded))
+}
+
+// ExtractInlineLogo moves an inline base64 "logo" out of a design into
+// the asset store and replaces it with "logoAssetId". Designs without
+// one are left alone.
+func (s *service) ExtractInlineLogo(ctx context.Context, userID string, design map[string]interface{}) error {
+	logo, _ := design["logo"].(string)
+	if logo == "" {
+		return nil
+	}
+
+	a, err := s.ImportInline(ctx, userID, logo)
+	if err != nil {
+		return err
+	}
+	design["logoAssetId"] = a.ID
+	delete(design, "logo")
+	return nil
+}
+
+func (s *service) Get(ctx context.Context, userID, id string) (*Asset, error) {
+	a, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrNotFound
+	}
+	return a, nil
+}
+
+// Open returns the asset with a reader for its bytes; close it when done
+func (s *service) Open(ctx context.Context, userID, id string) (*Asset, io.ReadCloser, error) {
+	a, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.store.Get(ctx, a.Key())
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, rc, nil
+}
+
+func (s *service) LoadImage(ctx context.Context, userID, id string) (image.Image, error) {
+	_, rc, err := s.Open(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	img, _, err := image.Decode(rc)
+	return img, err
+}
+
+func (s *service) List(ctx context.Context, userID string) ([]Asset, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Delete only removes the user's reference; the blob is content
+// addressed and may be shared with other assets. Logos still in use are
+// kept, their codes would lose them silently.
+func (s *service) Delete(ctx context.Context, userID, id string) error {
+	used, err := s.repo.InUse(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrInUse
+	}
+	return s.repo.Delete(ctx, id, userID)
+}
@@ -0,0 +1,109 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"qr-saas/internal/config"
+)
+
+// Store keeps asset bytes by key. Keys are derived from the content
+// hash (or are otherwise never reused, like export archives), so writing
+// the same key twice always writes the same bytes.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, mime string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var ErrBlobNotFound = errors.New("asset content not found")
+
+// contentKey spreads blobs over two directory levels, e.g.
+// sha256/ab/cd/abcd1234...
+func contentKey(sum string) string {
+	return fmt.Sprintf("sha256/%s/%s/%s", sum[:2], sum[2:4], sum)
+}
+
+// NewStore picks the backend from ASSET_STORE (local or s3)
+func NewStore(cfg config.Config) (Store, error) {
+	switch cfg.AssetStore {
+	case "", "local":
+		return NewLocalStore(cfg.AssetDir), nil
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			UseSSL:    cfg.S3UseSSL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown ASSET_STORE %q (want local or s3)", cfg.AssetStore)
+	}
+}
+
+// LocalStore writes blobs under a root directory
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root: root}
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, mime string) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename so readers never see half a blob
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qr/render"
+	"qr-saas/internal/scenes"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	svc Service
+}
+
+func RegisterRoutes(r *gin.RouterGroup, svc Service) {
+	h := &Handler{svc}
+
+	r.POST("", h.Import)
+}
+
+// @Summary Create QR codes from a CSV
+// @Description One code per row, all with the same design. Columns: name, qr_type (dynamic, static or vcard), symbology, target_url for dynamic codes, content for static ones, for vCards full_name, first_name, last_name, company, title, phone (cell), work_phone, home_phone, fax_phone, email (work), home_email, website, note, photo_url, vcard_format, a work address in street, city, region, postal_code and country, and profile URLs in linkedin, x, instagram, facebook, github, youtube and tiktok. Up to 1000 rows; any invalid row rejects the whole file with its line number. output zip or pdf starts an export job for the new codes.
+// @Tags QR
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV with a header row"
+// @Param options formData string false "Options as JSON"
+// @Success 201 {object} Result
+// @Router /api/qr/batch [post]
+func (h *Handler) Import(c *gin.Context) {
+	var opts Options
+	if raw := c.PostForm("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid options: " + err.Error()})
+			return
+		}
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable upload"})
+		return
+	}
+	defer f.Close()
+
+	res, err := h.svc.Import(c.Request.Context(), c.GetString("user_id"), f, opts)
+	var invalid *InvalidRowsError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rows, nothing was created", "rows": invalid.Rows})
+		return
+	}
+	if errors.Is(err, ErrFileTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if isRequestError(err) || qr.IsDesignError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import QR codes"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+// isRequestError reports a file or option the client got wrong
+func isRequestError(err error) bool {
+	return errors.Is(err, ErrTooManyRows) ||
+		errors.Is(err, ErrNoRows) ||
+		errors.Is(err, ErrInvalidCSV) ||
+		errors.Is(err, ErrUnknownOutput) ||
+		errors.Is(err, render.ErrUnknownSheet) ||
+		errors.Is(err, render.ErrInvalidSheet) ||
+		errors.Is(err, render.ErrUnsupportedFormat) ||
+		errors.Is(err, render.ErrInvalidSize) ||
+		errors.Is(err, render.ErrInvalidDPI) ||
+		errors.Is(err, render.ErrInvalidMM) ||
+		errors.Is(err, render.ErrTooManyPixels) ||
+		errors.Is(err, scenes.ErrNotFound)
+}
@@ -0,0 +1,72 @@
+package batch
+
+import (
+	"fmt"
+	"strings"
+
+	"qr-saas/internal/export"
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qrtypes"
+)
+
+// Options apply to every row of an import: defaults for rows without a
+// qr_type or symbology column, the shared design and what to print.
+type Options struct {
+	QRType           string `json:"qr_type"` // dynamic (default), static or vcard
+	Symbology        string `json:"symbology"`
+	Design           any    `json:"design"`     // same as on /api/qr/dynamic/url
+	ProjectID        string `json:"project_id"` // the new codes are added to this project
+	EnforceScannable bool   `json:"enforce_scannable"`
+
+	Output string               `json:"output"` // none (default), zip or pdf
+	ZIP    export.Request       `json:"zip"`    // render options for zip output, the selection is ignored
+	Labels export.LabelsRequest `json:"labels"` // label sheet for pdf output, the selection is ignored
+}
+
+// Outputs built from the created codes
+const (
+	OutputNone = "none"
+	OutputZIP  = "zip"
+	OutputPDF  = "pdf"
+)
+
+// Row is one CSV record, resolved to what CreateDynamicURL takes
+type Row struct {
+	Line      int    `json:"line"`
+	Name      string `json:"name"`
+	QRType    string `json:"qr_type"`
+	Symbology string `json:"symbology"`
+	Content   string `json:"content"` // target URL of a dynamic code, payload of a static one
+
+	VCard *qrtypes.VCardData `json:"-"` // data of a vcard row, typed after creation
+}
+
+// RowError points at the CSV line (the header is line 1) a problem is on
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Result of an import. Errors are rows that were valid but failed to
+// save; Job builds the zip or pdf, poll it at /api/qr/export/{id}.
+// OutputError says why there is no Job when an output was asked for.
+type Result struct {
+	Created     []qr.QRCode `json:"created"`
+	Errors      []RowError  `json:"errors"`
+	Job         *export.Job `json:"job,omitempty"`
+	OutputError string      `json:"output_error,omitempty"`
+}
+
+// InvalidRowsError rejects an import with invalid rows; nothing is
+// created until every row passes.
+type InvalidRowsError struct {
+	Rows []RowError
+}
+
+func (e *InvalidRowsError) Error() string {
+	msgs := make([]string, 0, len(e.Rows))
+	for _, r := range e.Rows {
+		msgs = append(msgs, fmt.Sprintf("line %d: %s", r.Line, r.Error))
+	}
+	return fmt.Sprintf("%d invalid rows: %s", len(e.Rows), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,351 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"qr-saas/internal/export"
+	"qr-saas/internal/projects"
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qr/render"
+	"qr-saas/internal/qrtypes"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Service interface {
+	// Import creates one code per CSV row with the shared design and
+	// optionally starts a zip or label sheet job for them. Every row is
+	// checked first; with any invalid row it returns *InvalidRowsError
+	// and creates nothing.
+	Import(ctx context.Context, userID string, r io.Reader, opts Options) (*Result, error)
+}
+
+// Import limits
+const (
+	MaxRows     = 1000
+	MaxFileSize = 5 << 20 // bytes
+)
+
+var (
+	ErrFileTooLarge    = fmt.Errorf("csv exceeds %d bytes", MaxFileSize)
+	ErrTooManyRows     = fmt.Errorf("csv has more than %d rows", MaxRows)
+	ErrNoRows          = errors.New("csv has no rows")
+	ErrInvalidCSV      = errors.New("invalid csv")
+	ErrUnknownOutput   = errors.New("output must be none, zip or pdf")
+	ErrProjectNotFound = errors.New("project not found")
+)
+
+type service struct {
+	qr       qr.Service
+	types    qrtypes.Service
+	projects projects.Service
+	export   export.Service
+}
+
+func NewService(qrSvc qr.Service, typesSvc qrtypes.Service, projectsSvc projects.Service, exportSvc export.Service) Service {
+	return &service{qr: qrSvc, types: typesSvc, projects: projectsSvc, export: exportSvc}
+}
+
+func (s *service) Import(ctx context.Context, userID string, r io.Reader, opts Options) (*Result, error) {
+	if err := s.checkOptions(ctx, userID, &opts); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	rows, err := parseRows(data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Created: []qr.QRCode{}, Errors: []RowError{}}
+	design := opts.Design
+	for _, row := range rows {
+		// Structured codes are created static and typed by qrtypes, which
+		// stores their data
+		qrType := row.QRType
+		if row.VCard != nil {
+			qrType = "static"
+		}
+		code, err := s.qr.CreateDynamicURL(ctx, userID, row.Name, row.Content, qrType, row.Symbology, design, opts.EnforceScannable)
+		if err != nil {
+			// A design the service refuses fails the same way on every row
+			if len(res.Created) == 0 && qr.IsDesignError(err) {
+				return nil, err
+			}
+			res.Errors = append(res.Errors, RowError{Line: row.Line, Error: err.Error()})
+			continue
+		}
+		if row.VCard != nil {
+			// The card was checked with the row, so this is a storage
+			// error; don't leave a plain static code behind
+			typed, err := s.types.CreateVCard(ctx, userID, code.ID, *row.VCard, qrtypes.PageOptions{})
+			if err != nil {
+				if delErr := s.qr.Delete(ctx, code.ID, userID); delErr != nil {
+					fmt.Printf("⚠️ batch import: untyped vCard code %s not deleted: %v\n", code.ID, delErr)
+				}
+				res.Errors = append(res.Errors, RowError{Line: row.Line, Error: err.Error()})
+				continue
+			}
+			code = typed
+		}
+		if len(res.Created) == 0 {
+			// Reuse the stored design, an inline logo is uploaded only once
+			design = json.RawMessage(code.DesignJSON)
+		}
+
+		if opts.ProjectID != "" {
+			if err := s.projects.AssignQR(ctx, userID, code.ID, opts.ProjectID); err != nil {
+				res.Errors = append(res.Errors, RowError{Line: row.Line, Error: "created but not added to the project: " + err.Error()})
+			} else {
+				code.ProjectID = &opts.ProjectID
+			}
+		}
+		res.Created = append(res.Created, *code)
+	}
+
+	if len(res.Created) == 0 {
+		return res, nil
+	}
+	switch opts.Output {
+	case OutputZIP:
+		res.Job, err = s.export.Start(ctx, userID, res.Created, opts.ZIP)
+	case OutputPDF:
+		res.Job, err = s.export.StartLabels(ctx, userID, res.Created, opts.Labels)
+	}
+	if err != nil {
+		// The codes exist either way, the client can still export them
+		fmt.Printf("⚠️ batch import output for user %s: %v\n", userID, err)
+		res.OutputError = err.Error()
+	}
+	return res, nil
+}
+
+// checkOptions validates everything that isn't per row before the upload
+// is parsed, so a bad sheet doesn't surface after the codes were created
+func (s *service) checkOptions(ctx context.Context, userID string, opts *Options) error {
+	opts.Output = strings.ToLower(strings.TrimSpace(opts.Output))
+	switch opts.Output {
+	case "", OutputNone:
+		opts.Output = OutputNone
+	case OutputZIP:
+		if err := s.export.CheckRender(ctx, opts.ZIP); err != nil {
+			return err
+		}
+	case OutputPDF:
+		if err := s.export.CheckLabels(opts.Labels); err != nil {
+			return err
+		}
+	default:
+		return ErrUnknownOutput
+	}
+
+	if opts.ProjectID != "" {
+		if _, err := s.projects.GetProject(ctx, userID, opts.ProjectID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrProjectNotFound
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// columnAliases maps alternative header names to the canonical ones
+var columnAliases = map[string]string{
+	"url":    "target_url",
+	"type":   "qr_type",
+	"format": "symbology",
+	"data":   "content",
+	"text":   "content",
+}
+
+// parseRows reads the header and resolves every record, collecting all
+// row errors rather than stopping at the first
+func parseRows(data []byte, opts Options) ([]Row, error) {
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, ErrNoRows
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if alias, ok := columnAliases[h]; ok {
+			h = alias
+		}
+		if _, dup := columns[h]; !dup {
+			columns[h] = i
+		}
+	}
+
+	var rows []Row
+	var invalid []RowError
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		if len(rows)+len(invalid) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row, err := resolveRow(field, opts)
+		if err != nil {
+			invalid = append(invalid, RowError{Line: line, Error: err.Error()})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+
+	if len(invalid) > 0 {
+		return nil, &InvalidRowsError{Rows: invalid}
+	}
+	if len(rows) == 0 {
+		return nil, ErrNoRows
+	}
+	return rows, nil
+}
+
+// vcardSocials are the networks with a profile URL column of their own
+var vcardSocials = []string{"linkedin", "x", "instagram", "facebook", "github", "youtube", "tiktok"}
+
+// vcardRow reads the vCard columns of a record. Phones and emails have a
+// column per type, the address is one work address.
+func vcardRow(field func(string) string) qrtypes.VCardData {
+	card := qrtypes.VCardData{
+		FullName:  field("full_name"),
+		FirstName: field("first_name"),
+		LastName:  field("last_name"),
+		Company:   field("company"),
+		Title:     field("title"),
+		Phone:     field("phone"),
+		Email:     field("email"),
+		Website:   field("website"),
+		Note:      field("note"),
+		PhotoURL:  field("photo_url"),
+		Format:    field("vcard_format"),
+	}
+	for _, t := range []string{"work", "home", "fax"} {
+		if v := field(t + "_phone"); v != "" {
+			card.Phones = append(card.Phones, qrtypes.TypedValue{Type: t, Value: v})
+		}
+	}
+	if v := field("home_email"); v != "" {
+		card.Emails = append(card.Emails, qrtypes.TypedValue{Type: "home", Value: v})
+	}
+	addr := qrtypes.Address{
+		Street:     field("street"),
+		City:       field("city"),
+		Region:     field("region"),
+		PostalCode: field("postal_code"),
+		Country:    field("country"),
+	}
+	if addr != (qrtypes.Address{}) {
+		card.Address = &addr
+	}
+	for _, network := range vcardSocials {
+		if u := field(network); u != "" {
+			card.Socials = append(card.Socials, qrtypes.SocialProfile{Network: network, URL: u})
+		}
+	}
+	return card
+}
+
+// resolveRow turns a record into the code to create: the target URL for
+// dynamic codes, the payload built from the vCard columns, or the
+// content column as is
+func resolveRow(field func(string) string, opts Options) (Row, error) {
+	row := Row{
+		Name:      field("name"),
+		QRType:    strings.ToLower(field("qr_type")),
+		Symbology: field("symbology"),
+	}
+	if row.QRType == "" {
+		row.QRType = strings.ToLower(strings.TrimSpace(opts.QRType))
+	}
+	if row.QRType == "" {
+		row.QRType = "dynamic"
+	}
+	if row.Symbology == "" {
+		row.Symbology = opts.Symbology
+	}
+
+	switch row.QRType {
+	case "dynamic", "url":
+		row.Content = field("target_url")
+		if !isWebURL(row.Content) {
+			return row, errors.New("target_url must be an http(s) URL")
+		}
+	case "vcard":
+		card := vcardRow(field)
+		payload, err := card.Payload()
+		if err != nil {
+			return row, err
+		}
+		row.Content = payload
+		row.VCard = &card
+		if row.Name == "" {
+			row.Name = card.FullName
+		}
+	case "static", "text":
+		row.Content = field("content")
+		if row.Content == "" {
+			return row, errors.New("content is required for static codes")
+		}
+	default:
+		return row, fmt.Errorf("unknown qr_type %q", row.QRType)
+	}
+
+	symbology, err := render.NormalizeSymbology(row.Symbology)
+	if err != nil {
+		return row, err
+	}
+	row.Symbology = symbology
+	dynamic := row.QRType == "dynamic" || row.QRType == "url"
+	if dynamic && render.IsLinear(symbology) {
+		return row, qr.ErrLinearNeedsStatic
+	}
+	if !dynamic {
+		// Static payloads have to fit the symbol (digits, capacity)
+		if err := render.ValidateContent(symbology, row.Content); err != nil {
+			return row, err
+		}
+	}
+	return row, nil
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
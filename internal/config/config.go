@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -33,6 +34,24 @@ type Config struct {
     
     // FIX 2: Add BaseURL (The public host of this API)
 	BaseURL string
+
+	// Directory of builtin mockup scene definitions (*.json)
+	ScenesDir string
+
+	// Uploaded assets (logos): "local" keeps them under AssetDir,
+	// "s3" in an S3-compatible bucket (AWS, MinIO, ...)
+	AssetStore  string
+	AssetDir    string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For is believed. Empty
+	// trusts none, client IPs are then the connection's.
+	TrustedProxies []string
 }
 
 func Load() Config {
@@ -71,6 +90,19 @@ func Load() Config {
         
         // Read Base URL (used by QR image generation for tracking)
 		BaseURL: getEnv("BASE_URL", "http://localhost:8080"), 
+
+		ScenesDir: getEnv("SCENES_DIR", "assets/scenes"),
+
+		AssetStore:  getEnv("ASSET_STORE", "local"),
+		AssetDir:    getEnv("ASSET_DIR", "data/assets"),
+		S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+		S3Region:    getEnv("S3_REGION", ""),
+		S3Bucket:    getEnv("S3_BUCKET", ""),
+		S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+		S3UseSSL:    getEnv("S3_USE_SSL", "true") == "true",
+
+		TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
 	}
 
 	fmt.Println("CLICKHOUSE_HOST LOADED =>", cfg.ClickHouseDSN)
@@ -84,6 +116,17 @@ func Load() Config {
 	return cfg
 }
 
+// splitList reads a comma separated env value, nil when empty
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func getEnv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -0,0 +1,201 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qr/render"
+	"qr-saas/internal/scenes"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	svc Service
+}
+
+func RegisterRoutes(r *gin.RouterGroup, svc Service) {
+	h := &Handler{svc}
+
+	r.POST("", h.Export)
+	r.POST("/labels", h.Labels)
+	r.GET("/sheets", h.Sheets)
+	r.GET("/:id", h.GetJob)
+	r.GET("/:id/download", h.Download)
+}
+
+// @Summary Export QR images as a ZIP
+// @Description Renders the selected codes (ids, a project_id, or every code, narrowed by filter) into a ZIP with a manifest.csv. Up to 25 codes are streamed back right away; bigger exports (or async=true) answer 202 with a job to poll.
+// @Tags QR
+// @Security BearerAuth
+// @Accept json
+// @Produce application/zip
+// @Produce json
+// @Param data body Request true "selection and render options"
+// @Success 200 {file} file "ZIP archive"
+// @Success 202 {object} Job
+// @Router /api/qr/export [post]
+func (h *Handler) Export(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	codes, err := h.svc.Select(c.Request.Context(), userID, req)
+	if errors.Is(err, ErrQRNotFound) || errors.Is(err, ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if isRequestError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to select QR codes"})
+		return
+	}
+
+	if req.Async || len(codes) > SyncLimit {
+		job, err := h.svc.Start(c.Request.Context(), userID, codes, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	// Errors past this point can't change the status any more; per code
+	// failures end up in manifest.csv
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, archiveName()))
+	c.Status(http.StatusOK)
+	if err := h.svc.WriteZIP(c.Request.Context(), c.Writer, userID, codes, req); err != nil {
+		fmt.Printf("❌ export stream for user %s: %v\n", userID, err)
+	}
+}
+
+// @Summary Print QR codes on label sheets
+// @Description Lays the selected codes out on label stock (a preset sheet or a custom grid in mm) as a multi-page PDF, one code per label with an optional caption such as "{name}" or "{short_code}".
+// @Tags QR
+// @Security BearerAuth
+// @Accept json
+// @Produce application/pdf
+// @Param data body LabelsRequest true "selection and sheet"
+// @Success 200 {file} file "PDF"
+// @Router /api/qr/export/labels [post]
+func (h *Handler) Labels(c *gin.Context) {
+	var req LabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	pdf, err := h.svc.Labels(c.Request.Context(), c.GetString("user_id"), req)
+	if errors.Is(err, ErrQRNotFound) || errors.Is(err, ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if isRequestError(err) || qr.IsDesignError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render labels"})
+		return
+	}
+
+	name := "qr-labels-" + time.Now().UTC().Format("2006-01-02") + ".pdf"
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Data(http.StatusOK, render.ContentType(render.FormatPDF), pdf)
+}
+
+// @Summary List label sheet presets
+// @Tags QR
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} render.SheetLayout
+// @Router /api/qr/export/sheets [get]
+func (h *Handler) Sheets(c *gin.Context) {
+	c.JSON(http.StatusOK, render.SheetPresets())
+}
+
+// @Summary Get a background export
+// @Tags QR
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Export job ID"
+// @Success 200 {object} Job
+// @Router /api/qr/export/{id} [get]
+func (h *Handler) GetJob(c *gin.Context) {
+	job, err := h.svc.GetJob(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Download a finished export
+// @Tags QR
+// @Security BearerAuth
+// @Produce application/zip
+// @Produce application/pdf
+// @Param id path string true "Export job ID"
+// @Success 200 {file} file "ZIP archive"
+// @Router /api/qr/export/{id}/download [get]
+func (h *Handler) Download(c *gin.Context) {
+	rc, job, err := h.svc.OpenDownload(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrJobNotReady) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+		return
+	}
+	defer rc.Close()
+
+	name := "qr-codes-" + job.CreatedAt.Format("2006-01-02") + "." + job.Kind
+	if job.Kind == KindPDF {
+		name = "qr-labels-" + job.CreatedAt.Format("2006-01-02") + ".pdf"
+	}
+	c.Header("Content-Type", job.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, rc)
+}
+
+func archiveName() string {
+	return "qr-codes-" + time.Now().UTC().Format("2006-01-02") + ".zip"
+}
+
+// isRequestError reports a selection or render option the client got wrong
+func isRequestError(err error) bool {
+	return errors.Is(err, ErrEmptySelection) ||
+		errors.Is(err, ErrTooManyCodes) ||
+		errors.Is(err, render.ErrUnknownSheet) ||
+		errors.Is(err, render.ErrInvalidSheet) ||
+		errors.Is(err, render.ErrUnsupportedFormat) ||
+		errors.Is(err, render.ErrInvalidSize) ||
+		errors.Is(err, render.ErrInvalidDPI) ||
+		errors.Is(err, render.ErrInvalidMM) ||
+		errors.Is(err, render.ErrTooManyPixels) ||
+		errors.Is(err, qr.ErrSceneNeedsPNG) ||
+		errors.Is(err, scenes.ErrNotFound)
+}
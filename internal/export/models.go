@@ -0,0 +1,85 @@
+package export
+
+import (
+	"time"
+
+	"qr-saas/internal/qr/render"
+)
+
+// Selection picks the codes of an export: IDs or ProjectID (all of the
+// user's codes when both are empty), narrowed down by Filter.
+type Selection struct {
+	IDs       []string `json:"ids"`
+	ProjectID string   `json:"project_id"`
+	Filter    Filter   `json:"filter"`
+}
+
+// Request is a ZIP export of the selected codes
+type Request struct {
+	Selection
+
+	Format  string  `json:"format"`   // png (default), jpeg, webp, svg or pdf
+	Size    int     `json:"size"`     // width in px
+	DPI     int     `json:"dpi"`      // with width_mm, or stored in png/jpeg metadata
+	WidthMM float64 `json:"width_mm"` // physical width, rendered at dpi
+	Scene   string  `json:"scene"`    // plain (default) or a scene id, png only
+
+	// Async runs the export as a background job even when it's small
+	Async bool `json:"async"`
+}
+
+// LabelsRequest prints the selected codes on label sheets. Sheet names a
+// preset (see render.SheetPresets); Layout is a custom grid instead.
+type LabelsRequest struct {
+	Selection
+
+	Sheet    string              `json:"sheet"`
+	Layout   *render.SheetLayout `json:"layout"`
+	Caption  string              `json:"caption"`  // e.g. "{name}", overrides the design's caption
+	Outlines bool                `json:"outlines"` // hairlines around labels for a test print
+}
+
+type Filter struct {
+	QRType    string `json:"qr_type"`
+	Symbology string `json:"symbology"`
+	Search    string `json:"search"`    // case-insensitive match on the name
+	Active    *bool  `json:"is_active"` // nil exports active and inactive codes
+}
+
+// Job output kinds, also the file extension
+const (
+	KindZIP = "zip"
+	KindPDF = "pdf"
+)
+
+// Job statuses
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Job is a background export, kept in Redis until it expires
+type Job struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Kind        string     `json:"kind"` // zip or pdf
+	Status      string     `json:"status"`
+	Total       int        `json:"total"`
+	Done        int        `json:"done"`
+	Failed      int        `json:"failed"` // codes that could not be rendered, see manifest.csv
+	Error       string     `json:"error,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+// ContentType is the MIME type of the job's file
+func (j *Job) ContentType() string {
+	if j.Kind == KindPDF {
+		return "application/pdf"
+	}
+	return "application/zip"
+}
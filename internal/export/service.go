@@ -0,0 +1,518 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"qr-saas/internal/assets"
+	"qr-saas/internal/projects"
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qr/render"
+	"qr-saas/internal/scenes"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+type Service interface {
+	// Select resolves the request to the user's codes, in export order
+	Select(ctx context.Context, userID string, req Request) ([]qr.QRCode, error)
+	// Labels renders the selected codes on label sheets as one PDF
+	Labels(ctx context.Context, userID string, req LabelsRequest) ([]byte, error)
+	// WriteZIP renders codes into a ZIP with a manifest.csv. A code that
+	// fails to render is listed in the manifest with its error.
+	WriteZIP(ctx context.Context, w io.Writer, userID string, codes []qr.QRCode, req Request) error
+	// CheckRender and CheckLabels validate output options before there
+	// are codes to render
+	CheckRender(ctx context.Context, req Request) error
+	CheckLabels(req LabelsRequest) error
+	// Start builds the ZIP in the background
+	Start(ctx context.Context, userID string, codes []qr.QRCode, req Request) (*Job, error)
+	// StartLabels builds the label sheet PDF of codes in the background
+	StartLabels(ctx context.Context, userID string, codes []qr.QRCode, req LabelsRequest) (*Job, error)
+	GetJob(ctx context.Context, userID, jobID string) (*Job, error)
+	OpenDownload(ctx context.Context, userID, jobID string) (io.ReadCloser, *Job, error)
+}
+
+// Exports up to SyncLimit codes are streamed straight back, bigger ones
+// run as a background job.
+const (
+	SyncLimit = 25
+	MaxCodes  = 5000
+)
+
+const (
+	jobTTL        = 24 * time.Hour
+	jobTimeout    = time.Hour
+	maxRunning    = 2  // background exports rendering at once, per process
+	progressEvery = 10 // codes between job updates in Redis
+)
+
+var (
+	ErrQRNotFound      = errors.New("qr code not found")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrEmptySelection  = errors.New("no QR codes match the export")
+	ErrTooManyCodes    = errors.New("too many QR codes in one export")
+	ErrJobNotFound     = errors.New("export not found or expired")
+	ErrJobNotReady     = errors.New("export is not finished yet")
+)
+
+type service struct {
+	qr       qr.Service
+	projects projects.Service
+	scenes   scenes.Service
+	store    assets.Store
+	rdb      *redis.Client
+	slots    chan struct{}
+}
+
+// NewService keeps job state in Redis and finished archives in the asset
+// store, so any API instance can answer status and download requests.
+func NewService(qrSvc qr.Service, projectsSvc projects.Service, scenesSvc scenes.Service, store assets.Store, rdb *redis.Client) Service {
+	return &service{
+		qr:       qrSvc,
+		projects: projectsSvc,
+		scenes:   scenesSvc,
+		store:    store,
+		rdb:      rdb,
+		slots:    make(chan struct{}, maxRunning),
+	}
+}
+
+func jobKey(id string) string { return "qrexport:job:" + id }
+
+// blobKey is where the finished file of a job is stored
+func blobKey(job *Job) string {
+	return "exports/" + job.ID + "." + job.Kind
+}
+
+const expiryKey = "qrexport:expiry" // sorted set of blob keys by expiry, for cleanup
+
+func (s *service) Select(ctx context.Context, userID string, req Request) ([]qr.QRCode, error) {
+	if err := s.checkRender(ctx, req); err != nil {
+		return nil, err
+	}
+	return s.selectCodes(ctx, userID, req.Selection, MaxCodes)
+}
+
+func (s *service) Labels(ctx context.Context, userID string, req LabelsRequest) ([]byte, error) {
+	layout, err := sheetLayout(req)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.selectCodes(ctx, userID, req.Selection, render.MaxSheetLabels)
+	if err != nil {
+		return nil, err
+	}
+	return s.qr.RenderSheet(ctx, userID, codes, layout, req.Caption, req.Outlines)
+}
+
+// sheetLayout is the custom layout, or the named preset
+func sheetLayout(req LabelsRequest) (render.SheetLayout, error) {
+	if req.Layout != nil {
+		return *req.Layout, req.Layout.Validate()
+	}
+	return render.LookupSheet(req.Sheet)
+}
+
+// selectCodes resolves a selection to at most limit of the user's codes
+func (s *service) selectCodes(ctx context.Context, userID string, req Selection, limit int) ([]qr.QRCode, error) {
+	tooMany := fmt.Errorf("%w (at most %d)", ErrTooManyCodes, limit)
+
+	var codes []qr.QRCode
+	switch {
+	case len(req.IDs) > 0:
+		if len(req.IDs) > limit {
+			return nil, tooMany
+		}
+		seen := make(map[string]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			code, err := s.qr.GetQR(ctx, id, userID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Errorf("%w: %s", ErrQRNotFound, id)
+			}
+			if err != nil {
+				return nil, err
+			}
+			codes = append(codes, *code)
+		}
+	case req.ProjectID != "":
+		if _, err := s.projects.GetProject(ctx, userID, req.ProjectID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+		list, err := s.projects.ListProjectQRs(ctx, userID, req.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		codes = list
+	default:
+		list, err := s.qr.ListByUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		codes = list
+	}
+
+	codes = req.Filter.apply(codes)
+	if len(codes) == 0 {
+		return nil, ErrEmptySelection
+	}
+	if len(codes) > limit {
+		return nil, tooMany
+	}
+	return codes, nil
+}
+
+// checkRender rejects a bad format, size or scene up front, before a
+// streamed response has started or a job was queued
+func (s *service) checkRender(ctx context.Context, req Request) error {
+	format, err := render.NormalizeFormat(req.Format)
+	if err != nil {
+		return err
+	}
+	if req.Size != 0 || req.WidthMM != 0 || req.DPI != 0 {
+		if _, err := (render.SizeOptions{Pixels: req.Size, DPI: req.DPI, WidthMM: req.WidthMM}).Resolve(format); err != nil {
+			return err
+		}
+	}
+	if req.Scene == "" || req.Scene == "plain" {
+		return nil
+	}
+	if format != render.FormatPNG {
+		return qr.ErrSceneNeedsPNG
+	}
+	_, err = s.scenes.Get(ctx, req.Scene)
+	return err
+}
+
+func (f Filter) apply(codes []qr.QRCode) []qr.QRCode {
+	search := strings.ToLower(strings.TrimSpace(f.Search))
+	symbology := ""
+	if f.Symbology != "" {
+		symbology, _ = render.NormalizeSymbology(f.Symbology)
+	}
+
+	var out []qr.QRCode
+	for _, c := range codes {
+		if f.QRType != "" && c.QRType != f.QRType {
+			continue
+		}
+		if f.Symbology != "" && c.Symbology != symbology {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(c.Name), search) {
+			continue
+		}
+		if f.Active != nil && c.IsActive != *f.Active {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (s *service) WriteZIP(ctx context.Context, w io.Writer, userID string, codes []qr.QRCode, req Request) error {
+	return s.writeZIP(ctx, w, userID, codes, req, nil)
+}
+
+// writeZIP reports progress after every code when progress is set
+func (s *service) writeZIP(ctx context.Context, w io.Writer, userID string, codes []qr.QRCode, req Request, progress func(done, failed int)) error {
+	format, err := render.NormalizeFormat(req.Format)
+	if err != nil {
+		return err
+	}
+	opts := qr.ImageOptions{Scene: req.Scene, Format: format, Size: req.Size, DPI: req.DPI, WidthMM: req.WidthMM}
+
+	// Raster images are compressed already
+	method := zip.Store
+	if render.IsVector(format) {
+		method = zip.Deflate
+	}
+
+	zw := zip.NewWriter(w)
+	var manifest strings.Builder
+	cw := csv.NewWriter(&manifest)
+	cw.Write([]string{"name", "short_code", "target_url", "symbology", "file_name", "error"})
+
+	failed := 0
+	for i, code := range codes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		fileName := ""
+		errText := ""
+		img, err := s.qr.GenerateQRImage(ctx, code.ID, userID, opts)
+		if err == nil {
+			fileName = FileName(code, format)
+			var f io.Writer
+			f, err = zw.CreateHeader(&zip.FileHeader{Name: fileName, Method: method, Modified: code.UpdatedAt})
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(img.Data); err != nil {
+				return err
+			}
+		} else {
+			failed++
+			errText = err.Error()
+			fmt.Printf("⚠️ export: QR %s: %v\n", code.ID, err)
+		}
+		cw.Write([]string{csvSafe(code.Name), code.ShortCode, csvSafe(code.TargetURL), code.Symbology, fileName, errText})
+
+		if progress != nil {
+			progress(i+1, failed)
+		}
+	}
+
+	cw.Flush()
+	f, err := zw.Create("manifest.csv")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, manifest.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// csvSafe keeps spreadsheet apps from running a cell as a formula
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// FileName is the archive entry for a code: its name as a slug plus the
+// short code, which keeps names unique, e.g. "table-12_aB3xK9.png".
+func FileName(code qr.QRCode, format string) string {
+	ext := format
+	if format == render.FormatJPEG {
+		ext = "jpg"
+	}
+	return slug(code.Name) + "_" + code.ShortCode + "." + ext
+}
+
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if b.Len() >= 40 {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	out := strings.TrimSuffix(b.String(), "-")
+	if out == "" {
+		return "qr"
+	}
+	return out
+}
+
+func (s *service) CheckRender(ctx context.Context, req Request) error {
+	return s.checkRender(ctx, req)
+}
+
+func (s *service) CheckLabels(req LabelsRequest) error {
+	_, err := sheetLayout(req)
+	return err
+}
+
+func (s *service) Start(ctx context.Context, userID string, codes []qr.QRCode, req Request) (*Job, error) {
+	if err := s.checkRender(ctx, req); err != nil {
+		return nil, err
+	}
+	return s.start(ctx, userID, KindZIP, len(codes), func(ctx context.Context, job *Job, w io.Writer) error {
+		return s.writeZIP(ctx, w, userID, codes, req, func(done, failed int) {
+			job.Done, job.Failed = done, failed
+			if done%progressEvery == 0 {
+				s.saveJob(ctx, job)
+			}
+		})
+	})
+}
+
+func (s *service) StartLabels(ctx context.Context, userID string, codes []qr.QRCode, req LabelsRequest) (*Job, error) {
+	layout, err := sheetLayout(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) > render.MaxSheetLabels {
+		return nil, render.ErrTooManyLabels
+	}
+	return s.start(ctx, userID, KindPDF, len(codes), func(ctx context.Context, job *Job, w io.Writer) error {
+		pdf, err := s.qr.RenderSheet(ctx, userID, codes, layout, req.Caption, req.Outlines)
+		if err != nil {
+			return err
+		}
+		job.Done = len(codes)
+		_, err = w.Write(pdf)
+		return err
+	})
+}
+
+// buildFunc writes the job's file to w, updating its progress on the way
+type buildFunc func(ctx context.Context, job *Job, w io.Writer) error
+
+func (s *service) start(ctx context.Context, userID, kind string, total int, build buildFunc) (*Job, error) {
+	s.sweep(ctx)
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Kind:      kind,
+		Status:    StatusQueued,
+		Total:     total,
+		CreatedAt: now,
+		ExpiresAt: now.Add(jobTTL),
+	}
+	if err := s.saveJob(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := s.rdb.ZAdd(ctx, expiryKey, redis.Z{Score: float64(job.ExpiresAt.Unix()), Member: blobKey(job)}).Err(); err != nil {
+		return nil, err
+	}
+
+	// The caller gets a snapshot, run keeps updating its own job
+	snapshot := *job
+	go s.run(job, build)
+	return &snapshot, nil
+}
+
+// run builds the file into a temp file and uploads it to the store. It
+// outlives the request, so it has its own context.
+func (s *service) run(job *Job, build buildFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	job.Status = StatusRunning
+	s.saveJob(ctx, job)
+
+	err := s.upload(ctx, job, build)
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	if err != nil {
+		fmt.Printf("❌ export %s failed: %v\n", job.ID, err)
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusDone
+		job.DownloadURL = "/api/qr/export/" + job.ID + "/download"
+		fmt.Printf("✅ export %s: %d codes, %d failed\n", job.ID, job.Total, job.Failed)
+	}
+	if err := s.saveJob(ctx, job); err != nil {
+		fmt.Printf("⚠️ export %s: saving job: %v\n", job.ID, err)
+	}
+}
+
+func (s *service) upload(ctx context.Context, job *Job, build buildFunc) error {
+	tmp, err := os.CreateTemp("", "qr-export-*."+job.Kind)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := build(ctx, job, tmp); err != nil {
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.store.Put(ctx, blobKey(job), tmp, size, job.ContentType())
+}
+
+func (s *service) saveJob(ctx context.Context, job *Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, jobKey(job.ID), raw, time.Until(job.ExpiresAt)).Err()
+}
+
+func (s *service) GetJob(ctx context.Context, userID, jobID string) (*Job, error) {
+	raw, err := s.rdb.Get(ctx, jobKey(jobID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (s *service) OpenDownload(ctx context.Context, userID, jobID string) (io.ReadCloser, *Job, error) {
+	job, err := s.GetJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != StatusDone {
+		return nil, nil, ErrJobNotReady
+	}
+	rc, err := s.store.Get(ctx, blobKey(job))
+	if errors.Is(err, assets.ErrBlobNotFound) {
+		return nil, nil, ErrJobNotFound
+	}
+	return rc, job, err
+}
+
+// sweep deletes the archives of expired jobs; Redis drops the job
+// records by itself.
+func (s *service) sweep(ctx context.Context) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	keys, err := s.rdb.ZRangeByScore(ctx, expiryKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		fmt.Printf("⚠️ export sweep: %v\n", err)
+		return
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			fmt.Printf("⚠️ export sweep %s: %v\n", key, err)
+			continue
+		}
+		s.rdb.ZRem(ctx, expiryKey, key)
+	}
+}
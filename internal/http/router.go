@@ -1,6 +1,7 @@
 package http
 
 import (
+	"log"
 	"os"
 	"time"
 
@@ -16,6 +17,11 @@ import (
 func NewRouter(redis *redis.Client, cfg config.Config) *gin.Engine {
 	r := gin.New()
 
+	// Client IPs (rate limits) only come from headers of known proxies
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("❌ TRUSTED_PROXIES:", err)
+	}
+
 	// ----------------------------------------------
 	// FIX: Dynamically configure CORS for Render/Vercel
 	// ----------------------------------------------
@@ -123,7 +123,7 @@ func (r *repository) Delete(ctx context.Context, userID, id string) error {
 func (r *repository) ListProjectQRs(ctx context.Context, userID, projectID string) ([]qr.QRCode, error) {
 	rows, err := r.pg.Query(ctx,
 		`SELECT 
-            id, user_id, project_id, name, qr_type, short_code,
+            id, user_id, project_id, name, qr_type, symbology, short_code,
             target_url, design_json, is_active, created_at, updated_at
          FROM qr_codes
          WHERE user_id=$1 AND project_id=$2
@@ -145,6 +145,7 @@ func (r *repository) ListProjectQRs(ctx context.Context, userID, projectID strin
 			&q.ProjectID,
 			&q.Name,
 			&q.QRType,
+			&q.Symbology,
 			&q.ShortCode,
 			&q.TargetURL,
 			&q.DesignJSON,
@@ -0,0 +1,96 @@
+package qr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"qr-saas/internal/qr/render"
+	"qr-saas/internal/scenes"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ImageCache keeps rendered images by render key. Keys are content
+// hashes, so a stale entry can never be served for a changed design;
+// Invalidate just frees the old entries of a QR early.
+type ImageCache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, qrID, key string, img []byte)
+	Invalidate(ctx context.Context, qrID string)
+}
+
+// renderCacheVersion is part of every key; bump it when renderer output
+// changes so old entries (and browser ETags) are dropped.
+const renderCacheVersion = "1"
+
+// renderKey hashes everything that affects the output image. It doubles
+// as the ETag. Short code and name are in because captions print them.
+func renderKey(qrData *QRCode, content string, size render.SizeOptions, format string, scene *scenes.Scene) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%d/%d/%g\x00%s\x00", renderCacheVersion, content,
+		qrData.Symbology, qrData.DesignJSON, qrData.ShortCode, qrData.Name,
+		size.Pixels, size.DPI, size.WidthMM, format)
+	if scene != nil {
+		sceneJSON, _ := json.Marshal(scene)
+		h.Write(sceneJSON)
+		h.Write([]byte(scene.BackgroundPath))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type redisImageCache struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+// NewRedisImageCache stores images as plain keys plus one set per QR
+// listing its keys, so UpdateQR can drop them all.
+func NewRedisImageCache(rdb *redis.Client, ttl time.Duration) ImageCache {
+	return &redisImageCache{rdb: rdb, ttl: ttl}
+}
+
+func imageKey(key string) string    { return "qrimg:" + key }
+func imageIndex(qrID string) string { return "qrimg:idx:" + qrID }
+
+func (c *redisImageCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	img, err := c.rdb.Get(ctx, imageKey(key)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return img, true
+}
+
+func (c *redisImageCache) Set(ctx context.Context, qrID, key string, img []byte) {
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, imageKey(key), img, c.ttl)
+	pipe.SAdd(ctx, imageIndex(qrID), key)
+	pipe.Expire(ctx, imageIndex(qrID), c.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		// on redis error, just render next time
+		fmt.Printf("⚠️ QR %s image cache write failed: %v\n", qrID, err)
+	}
+}
+
+func (c *redisImageCache) Invalidate(ctx context.Context, qrID string) {
+	keys, err := c.rdb.SMembers(ctx, imageIndex(qrID)).Result()
+	if err != nil {
+		return
+	}
+	del := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		del = append(del, imageKey(k))
+	}
+	del = append(del, imageIndex(qrID))
+	c.rdb.Del(ctx, del...)
+}
+
+// noopImageCache is used when no cache is configured
+type noopImageCache struct{}
+
+func (noopImageCache) Get(context.Context, string) ([]byte, bool)  { return nil, false }
+func (noopImageCache) Set(context.Context, string, string, []byte) {}
+func (noopImageCache) Invalidate(context.Context, string)          {}
@@ -1,7 +1,13 @@
 package qr
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+
+	"qr-saas/internal/assets"
+	"qr-saas/internal/qr/render"
+	"qr-saas/internal/scenes"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,8 +22,11 @@ func RegisterRoutes(r *gin.RouterGroup, svc Service) {
 	r.POST("/dynamic/url", h.CreateDynamicURL)
 	r.GET("/", h.ListMyQRCodes)
 	r.GET("/:id/image", h.GetQRImage)
+	r.POST("/:id/validate", h.ValidateQR)
 	r.GET("/:id", h.GetQR)
 	r.PUT("/:id", h.UpdateQR)
+	r.PUT("/:id/limits", h.SetLimits)
+	r.PUT("/:id/password", h.SetPassword)
 	r.DELETE("/:id", h.DeleteQR)
 }
 
@@ -25,15 +34,25 @@ type CreateDynamicURLRequest struct {
 	Name string `json:"name"`
 	// Removed "url" validation so it accepts WiFi/vCard strings
 	TargetURL string `json:"target_url" binding:"required"`
-	// 🔥 ADDED: Field to capture the type (wifi, vcard, etc.)
+	// dynamic, url, static or text; typed codes (wifi, vcard, ...) are set with /api/qr/types
 	QRType string      `json:"qr_type" binding:"required"`
 	Design interface{} `json:"design"`
+	// qr (default), datamatrix, aztec, pdf417, ean13, upca or code128
+	Symbology string `json:"symbology"`
+	// Reject designs that don't pass the scannability check
+	EnforceScannable bool `json:"enforce_scannable"`
 }
 
 type UpdateQRRequest struct {
-	Name      string      `json:"name"`
-	TargetURL string      `json:"target_url"`
-	Design    interface{} `json:"design"`
+	Name             string      `json:"name"`
+	TargetURL        string      `json:"target_url"`
+	Symbology        string      `json:"symbology"` // empty keeps the current one
+	Design           interface{} `json:"design"`
+	EnforceScannable bool        `json:"enforce_scannable"`
+}
+
+type ValidateQRRequest struct {
+	Scene string `json:"scene"` // optional scene id, checks the composite
 }
 
 // CreateDynamicURL godoc
@@ -62,8 +81,25 @@ func (h *Handler) CreateDynamicURL(c *gin.Context) {
 		req.Name,
 		req.TargetURL,
 		req.QRType, // <--- This was missing!
+		req.Symbology,
 		req.Design,
+		req.EnforceScannable,
 	)
+	if respondNotScannable(c, err) {
+		return
+	}
+	if errors.Is(err, ErrUnknownQRType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if isSymbologyError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid symbology: " + err.Error()})
+		return
+	}
+	if IsDesignError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid design: " + err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create QR: " + err.Error(),
@@ -75,27 +111,127 @@ func (h *Handler) CreateDynamicURL(c *gin.Context) {
 }
 
 // GetQRImage godoc
+// @Summary Render QR image
+// @Tags QR
+// @Produce png
+// @Produce image/svg+xml
+// @Produce jpeg
+// @Produce image/webp
+// @Produce application/pdf
+// @Param id path string true "QR ID"
+// @Param scene query string false "plain or a scene id from /api/scenes" default(plain)
+// @Param format query string false "png|jpeg|webp|svg|pdf" default(png)
+// @Param size query int false "width in px (64-4096)" default(600)
+// @Param width_mm query number false "physical width in mm, rendered at dpi"
+// @Param width_in query number false "physical width in inches, rendered at dpi"
+// @Param dpi query int false "72-1200, stored in png/jpeg metadata" default(300)
+// @Param If-None-Match header string false "ETag of a previously fetched image"
+// @Success 200
+// @Success 304 "image unchanged"
 // @Router /api/qr/{id}/image [get]
+// @Security BearerAuth
 func (h *Handler) GetQRImage(c *gin.Context) {
 	qrID := c.Param("id")
 	userID := c.GetString("user_id")
-	scene := c.DefaultQuery("scene", "plain")
+
+	format, err := render.NormalizeFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png, jpeg, webp, svg or pdf"})
+		return
+	}
+
+	size, err := queryInt(c, "size")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a number of pixels"})
+		return
+	}
+	dpi, err := queryInt(c, "dpi")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dpi must be a number"})
+		return
+	}
+	widthMM, err := queryFloat(c, "width_mm")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "width_mm must be a number"})
+		return
+	}
+	if widthMM == 0 {
+		widthIn, err := queryFloat(c, "width_in")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "width_in must be a number"})
+			return
+		}
+		widthMM = widthIn * 25.4
+	}
 
 	img, err := h.svc.GenerateQRImage(
 		c.Request.Context(),
 		qrID,
 		userID,
-		scene,
+		ImageOptions{
+			Scene:       c.DefaultQuery("scene", "plain"),
+			Format:      format,
+			IfNoneMatch: c.GetHeader("If-None-Match"),
+			Size:        size,
+			DPI:         dpi,
+			WidthMM:     widthMM,
+		},
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		if IsDesignError(err) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
 			"error": "Failed to generate image: " + err.Error(),
 		})
 		return
 	}
 
-	c.Header("Content-Type", "image/png")
-	c.Writer.Write(img)
+	// Private (needs the JWT) and revalidated every time; unchanged
+	// designs answer 304 without rendering
+	c.Header("ETag", `"`+img.ETag+`"`)
+	c.Header("Cache-Control", "private, no-cache")
+	if img.NotModified {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Type", render.ContentType(format))
+	c.Writer.Write(img.Data)
+}
+
+// ValidateQR godoc
+// @Summary Check that a QR design scans
+// @Description Renders the code, decodes it again and returns a 0-100 score with warnings
+// @Tags QR
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body ValidateQRRequest false "options"
+// @Success 200 {object} render.ScanReport
+// @Router /api/qr/{id}/validate [post]
+// @Security BearerAuth
+func (h *Handler) ValidateQR(c *gin.Context) {
+	var req ValidateQRRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	report, err := h.svc.ValidateQR(c.Request.Context(), c.Param("id"), c.GetString("user_id"), req.Scene)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if IsDesignError(err) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": "Failed to validate: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
 }
 
 func (h *Handler) ListMyQRCodes(c *gin.Context) {
@@ -144,7 +280,18 @@ func (h *Handler) UpdateQR(c *gin.Context) {
 		return
 	}
 
-	qr, err := h.svc.UpdateQR(c.Request.Context(), id, userID, req.Name, req.TargetURL, req.Design)
+	qr, err := h.svc.UpdateQR(c.Request.Context(), id, userID, req.Name, req.TargetURL, req.Symbology, req.Design, req.EnforceScannable)
+	if respondNotScannable(c, err) {
+		return
+	}
+	if isSymbologyError(err) {
+		c.JSON(400, gin.H{"error": "Invalid symbology: " + err.Error()})
+		return
+	}
+	if IsDesignError(err) {
+		c.JSON(400, gin.H{"error": "Invalid design: " + err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -152,3 +299,130 @@ func (h *Handler) UpdateQR(c *gin.Context) {
 
 	c.JSON(200, qr)
 }
+
+// SetLimits godoc
+// @Summary Limit when and how often a code redirects
+// @Description Replaces the validity window (valid_from, valid_until) and scan cap (max_scans) of a dynamic code. Scans outside of them get the limit's fallback: a redirect to its url, or a page with its title and message (a default page when unset). Bots don't count against max_scans; reset_scans starts the count over. Omitted limits are lifted.
+// @Tags QR
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body SetLimitsRequest true "limits"
+// @Success 200 {object} QRCode
+// @Router /api/qr/{id}/limits [put]
+func (h *Handler) SetLimits(c *gin.Context) {
+	var req SetLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	qr, err := h.svc.SetLimits(c.Request.Context(), c.Param("id"), c.GetString("user_id"), req)
+	switch {
+	case errors.Is(err, ErrQRNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidLimits), errors.Is(err, ErrLimitsNeedDynamic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save limits"})
+	default:
+		c.JSON(http.StatusOK, qr)
+	}
+}
+
+// SetPassword godoc
+// @Summary Password protect a code
+// @Description Scanners of the dynamic code get a password form before the redirect; the right password lets them through for an hour. Failed attempts are rate limited. An empty password removes the protection.
+// @Tags QR
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body SetPasswordRequest true "password"
+// @Success 200 {object} QRCode
+// @Router /api/qr/{id}/password [put]
+func (h *Handler) SetPassword(c *gin.Context) {
+	var req SetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	qr, err := h.svc.SetPassword(c.Request.Context(), c.Param("id"), c.GetString("user_id"), req.Password)
+	switch {
+	case errors.Is(err, ErrQRNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidPassword), errors.Is(err, ErrPasswordNeedsDynamic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save password"})
+	default:
+		c.JSON(http.StatusOK, qr)
+	}
+}
+
+// queryInt parses an optional integer query parameter (0 when absent)
+func queryInt(c *gin.Context, key string) (int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// queryFloat parses an optional decimal query parameter (0 when absent)
+func queryFloat(c *gin.Context, key string) (float64, error) {
+	v := c.Query(key)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// respondNotScannable answers 422 with the scan report when a save was
+// rejected by enforce_scannable
+func respondNotScannable(c *gin.Context, err error) bool {
+	var ns *NotScannableError
+	if !errors.As(err, &ns) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":  ns.Error(),
+		"report": ns.Report,
+	})
+	return true
+}
+
+// IsDesignError reports render failures caused by an invalid design
+// rather than by the server.
+func IsDesignError(err error) bool {
+	return errors.Is(err, render.ErrInvalidErrorCorrection) ||
+		errors.Is(err, render.ErrInvalidMargin) ||
+		errors.Is(err, render.ErrInvalidShape) ||
+		errors.Is(err, render.ErrInvalidGradient) ||
+		errors.Is(err, render.ErrLowContrast) ||
+		errors.Is(err, render.ErrInvalidFrame) ||
+		errors.Is(err, render.ErrInvalidCaption) ||
+		errors.Is(err, render.ErrUnknownFont) ||
+		errors.Is(err, render.ErrInvalidQuad) ||
+		errors.Is(err, scenes.ErrNotFound) ||
+		errors.Is(err, ErrSceneNeedsPNG) ||
+		errors.Is(err, render.ErrInvalidSize) ||
+		errors.Is(err, render.ErrInvalidDPI) ||
+		errors.Is(err, render.ErrInvalidMM) ||
+		errors.Is(err, render.ErrTooManyPixels) ||
+		errors.Is(err, assets.ErrNotFound) ||
+		errors.Is(err, assets.ErrTooLarge) ||
+		errors.Is(err, assets.ErrUnsupportedType) ||
+		errors.Is(err, assets.ErrTooManyPixels) ||
+		errors.Is(err, render.ErrScanUnsupported) ||
+		isSymbologyError(err)
+}
+
+// isSymbologyError reports a symbology that can't carry the code
+func isSymbologyError(err error) bool {
+	return errors.Is(err, render.ErrUnsupportedSymbology) ||
+		errors.Is(err, render.ErrInvalidContent) ||
+		errors.Is(err, ErrLinearNeedsStatic)
+}
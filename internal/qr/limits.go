@@ -0,0 +1,146 @@
+package qr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrQRNotFound is returned for a code that doesn't exist or isn't the user's
+var ErrQRNotFound = errors.New("qr code not found")
+
+// ErrInvalidLimits is returned for limits that can't be enforced
+var ErrInvalidLimits = errors.New("invalid limits")
+
+// ErrLimitsNeedDynamic is returned when limiting a static code, which
+// never goes through the redirect
+var ErrLimitsNeedDynamic = errors.New("only dynamic codes can expire or be capped")
+
+// What stopped a scan, see Limits.Blocked
+const (
+	LimitNotStarted = "not_started"
+	LimitExpired    = "expired"
+	LimitScanLimit  = "scan_limit"
+)
+
+const maxFallbackText = 500
+
+// SetLimitsRequest replaces all limits of a code, omitted ones are lifted
+type SetLimitsRequest struct {
+	Limits
+	ResetScans bool `json:"reset_scans"` // count max_scans from zero again
+}
+
+// Blocked is the window limit in effect at now, "" when the code
+// redirects. The scan cap is claimed separately.
+func (l Limits) Blocked(now time.Time) string {
+	switch {
+	case l.ValidFrom != nil && now.Before(*l.ValidFrom):
+		return LimitNotStarted
+	case l.ValidUntil != nil && !now.Before(*l.ValidUntil):
+		return LimitExpired
+	}
+	return ""
+}
+
+// Unavailable is the limit a scan at now runs into, "" when the code
+// still redirects. The cap is checked against the count as loaded,
+// ClaimScan enforces it.
+func (q *QRCode) Unavailable(now time.Time) string {
+	if limit := q.Blocked(now); limit != "" {
+		return limit
+	}
+	if q.MaxScans != nil && q.ScanCount >= *q.MaxScans {
+		return LimitScanLimit
+	}
+	return ""
+}
+
+// For returns the fallback configured for a limit, nil for the default page
+func (f Fallbacks) For(limit string) *Fallback {
+	switch limit {
+	case LimitNotStarted:
+		return f.NotStarted
+	case LimitExpired:
+		return f.Expired
+	case LimitScanLimit:
+		return f.ScanLimit
+	}
+	return nil
+}
+
+func (s *service) SetLimits(ctx context.Context, id, userID string, req SetLimitsRequest) (*QRCode, error) {
+	qr, err := s.repo.GetByID(ctx, id, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !IsDynamic(qr.QRType) {
+		return nil, ErrLimitsNeedDynamic
+	}
+
+	limits, err := req.Limits.normalized()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetLimits(ctx, qr.ID, userID, limits, req.ResetScans); err != nil {
+		return nil, err
+	}
+	qr.Limits = limits
+	if req.ResetScans {
+		qr.ScanCount = 0
+	}
+	return qr, nil
+}
+
+func (l Limits) normalized() (Limits, error) {
+	if l.ValidFrom != nil && l.ValidUntil != nil && !l.ValidUntil.After(*l.ValidFrom) {
+		return l, fmt.Errorf("%w: valid_until must be after valid_from", ErrInvalidLimits)
+	}
+	if l.MaxScans != nil && *l.MaxScans < 1 {
+		return l, fmt.Errorf("%w: max_scans must be at least 1", ErrInvalidLimits)
+	}
+	for name, f := range map[string]**Fallback{
+		LimitNotStarted: &l.Fallbacks.NotStarted,
+		LimitExpired:    &l.Fallbacks.Expired,
+		LimitScanLimit:  &l.Fallbacks.ScanLimit,
+	} {
+		n, err := (*f).normalized()
+		if err != nil {
+			return l, fmt.Errorf("%w: %s fallback: %v", ErrInvalidLimits, name, err)
+		}
+		*f = n
+	}
+	return l, nil
+}
+
+func (f *Fallback) normalized() (*Fallback, error) {
+	if f == nil {
+		return nil, nil
+	}
+	n := Fallback{
+		URL:     strings.TrimSpace(f.URL),
+		Title:   strings.TrimSpace(f.Title),
+		Message: strings.TrimSpace(f.Message),
+	}
+	if n.URL != "" {
+		u, err := url.Parse(n.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("url must be an http(s) link")
+		}
+	}
+	if len(n.Title) > maxFallbackText || len(n.Message) > maxFallbackText {
+		return nil, fmt.Errorf("title and message are limited to %d characters", maxFallbackText)
+	}
+	if n == (Fallback{}) {
+		return nil, nil
+	}
+	return &n, nil
+}
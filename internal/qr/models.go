@@ -8,10 +8,64 @@ type QRCode struct {
 	ProjectID  *string   `json:"project_id,omitempty"`
 	Name       string    `json:"name"`
 	QRType     string    `json:"qr_type"`
+	Symbology  string    `json:"symbology"` // qr, datamatrix, aztec, pdf417, ean13, upca, code128
 	ShortCode  string    `json:"short_code"`
 	TargetURL  string    `json:"target_url"`
 	DesignJSON string    `json:"design_json"`
 	IsActive   bool      `json:"is_active"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	Limits
+	ScanCount int `json:"scan_count"` // scans counted against max_scans
+
+	// Scanners must enter the password before being redirected
+	PasswordProtected bool   `json:"password_protected"`
+	PasswordHash      string `json:"-"` // bcrypt
+
+	// Design warnings (low contrast), returned by create and update
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Limits restrict when and how often a dynamic code redirects. Outside
+// of them a scan gets the matching fallback instead.
+type Limits struct {
+	ValidFrom  *time.Time `json:"valid_from"`  // not redirecting before
+	ValidUntil *time.Time `json:"valid_until"` // nor from then on
+	MaxScans   *int       `json:"max_scans"`   // bots (link previews) don't count
+	Fallbacks  Fallbacks  `json:"fallbacks"`
+}
+
+// Fallbacks per limit, a built-in page when unset
+type Fallbacks struct {
+	NotStarted *Fallback `json:"not_started,omitempty"` // before valid_from
+	Expired    *Fallback `json:"expired,omitempty"`     // from valid_until
+	ScanLimit  *Fallback `json:"scan_limit,omitempty"`  // max_scans reached
+}
+
+// Fallback redirects to URL, or shows a page with Title and Message
+type Fallback struct {
+	URL     string `json:"url,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ImageOptions controls how GenerateQRImage renders a code
+type ImageOptions struct {
+	Scene       string // "plain" or a scene id from the scenes registry
+	Format      string // png, jpeg, webp, svg or pdf
+	IfNoneMatch string // ETag the client already has
+
+	// Output size: Size px, or WidthMM printed at DPI. Zero means default.
+	Size    int
+	DPI     int
+	WidthMM float64
+}
+
+// RenderedImage is a rendered QR image. NotModified means the client's
+// ETag still matches and Data was not produced.
+type RenderedImage struct {
+	Data        []byte
+	ETag        string
+	NotModified bool
 }
@@ -0,0 +1,59 @@
+package qr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidPassword is returned for a password that can't protect a code
+var ErrInvalidPassword = errors.New("invalid password")
+
+// ErrPasswordNeedsDynamic is returned when protecting a static code, its
+// content is readable without the redirect
+var ErrPasswordNeedsDynamic = errors.New("only dynamic codes can be password protected")
+
+const (
+	minPasswordLen = 4
+	maxPasswordLen = 72 // bcrypt ignores the rest
+)
+
+// SetPasswordRequest protects a code, an empty password removes it
+type SetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+func (s *service) SetPassword(ctx context.Context, id, userID, password string) (*QRCode, error) {
+	qr, err := s.repo.GetByID(ctx, id, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !IsDynamic(qr.QRType) {
+		return nil, ErrPasswordNeedsDynamic
+	}
+
+	hash := ""
+	if password != "" {
+		if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+			return nil, fmt.Errorf("%w: use %d to %d characters", ErrInvalidPassword, minPasswordLen, maxPasswordLen)
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		hash = string(hashed)
+	}
+
+	if err := s.repo.SetPassword(ctx, qr.ID, userID, hash); err != nil {
+		return nil, err
+	}
+	qr.PasswordHash = hash
+	qr.PasswordProtected = hash != ""
+	return qr, nil
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Caption is a line of text printed above or below the code, e.g. the
+// short URL or an asset tag people can type in when the camera fails.
+type Caption struct {
+	Text     string  `json:"text"`     // printed as is; the QR service expands {short_url}, {short_code} and {name}
+	Position string  `json:"position"` // bottom (default) or top
+	Font     string  `json:"font"`     // one of FontNames(), defaults to DefaultCaptionFont
+	Size     float64 `json:"size"`     // font size in % of the code width, defaults to DefaultCaptionSize
+	Color    string  `json:"color"`    // Hex code, defaults to the code color
+}
+
+const (
+	CaptionBottom = "bottom"
+	CaptionTop    = "top"
+)
+
+// DefaultCaptionFont is monospaced so 0/O and 1/l stay apart in codes
+// people retype by hand
+const DefaultCaptionFont = "go-mono"
+
+const (
+	DefaultCaptionSize = 7.0
+	MinCaptionSize     = 2.0
+	MaxCaptionSize     = 20.0
+	MaxCaptionText     = 64 // characters
+)
+
+var ErrInvalidCaption = fmt.Errorf("caption needs text of at most %d characters, position top or bottom and a size between %g and %g", MaxCaptionText, MinCaptionSize, MaxCaptionSize)
+
+// Caption proportions, relative to the font size
+const (
+	captionLineHeight = 1.4
+	captionMaxWidth   = 0.94 // of the drawing width, long text is shrunk to fit
+)
+
+// applyCaption adds a band for the caption above or below the code,
+// outside its quiet zone, and returns the new, taller drawing.
+func applyCaption(code *drawing, c *Caption, st codeStyle) (*drawing, error) {
+	position, err := pickShape(c.Position, CaptionBottom, CaptionTop)
+	if err != nil {
+		return nil, ErrInvalidCaption
+	}
+
+	text := strings.TrimSpace(c.Text)
+	if text == "" || utf8.RuneCountInString(text) > MaxCaptionText {
+		return nil, ErrInvalidCaption
+	}
+
+	size := c.Size
+	if size == 0 {
+		size = DefaultCaptionSize
+	}
+	if size < MinCaptionSize || size > MaxCaptionSize {
+		return nil, ErrInvalidCaption
+	}
+
+	name := c.Font
+	if name == "" {
+		name = DefaultCaptionFont
+	}
+	font, err := loadFont(name)
+	if err != nil {
+		return nil, err
+	}
+
+	textColor := st.Foreground
+	if c.Color != "" {
+		textColor = parseHexColor(c.Color)
+	}
+
+	line := textLine{font: font, text: text, size: code.Width * size / 100}
+	band := line.size * captionLineHeight
+	if tw, limit := line.width(), code.Width*captionMaxWidth; tw > limit {
+		line.size *= limit / tw
+	}
+
+	d := &drawing{Width: code.Width, Height: code.Height + band, Background: code.Background}
+	top := code.Height
+	if position == CaptionTop {
+		d.Items = code.translated(0, band)
+		top = 0
+	} else {
+		d.Items = code.Items
+	}
+
+	x := (d.Width - line.width()) / 2
+	baseline := top + (band+line.capHeight())/2
+	d.fill(line.outline(x, baseline), solid(textColor))
+
+	return d, nil
+}
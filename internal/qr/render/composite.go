@@ -2,8 +2,11 @@ package render
 
 import (
 	"bytes"
+	"errors"
 	"image"
+	"image/color"
 	"image/png"
+	"math"
 	"os"
 
 	"github.com/disintegration/imaging"
@@ -16,8 +19,25 @@ type CompositeOptions struct {
 	PosY           int    // Y position
 	Width          int    // width to resize QR into
 	Height         int
+
+	// Quad, when set, replaces PosX/PosY/Width/Height: the QR is warped
+	// so its corners land on the four points (perspective, e.g. a tilted
+	// table tent or a mug).
+	Quad *Quad
+}
+
+// Corner is a point in background image pixels
+type Corner struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 
+// Quad is the placement of a QR on a background: top-left, top-right,
+// bottom-right and bottom-left corners, in that order.
+type Quad [4]Corner
+
+var ErrInvalidQuad = errors.New("placement quad must have four distinct, non-collinear corners")
+
 // Returns final composite PNG bytes
 func ComposeQROnBackground(opts CompositeOptions) ([]byte, error) {
 	bgFile, err := os.Open(opts.BackgroundPath)
@@ -36,13 +56,21 @@ func ComposeQROnBackground(opts CompositeOptions) ([]byte, error) {
 		return nil, err
 	}
 
-	// resize QR to fit “plate/box” area
-	if opts.Width > 0 && opts.Height > 0 {
-		qrImg = imaging.Resize(qrImg, opts.Width, opts.Height, imaging.Lanczos)
-	}
+	var composite *image.NRGBA
+	if opts.Quad != nil {
+		composite, err = warpOnto(bg, qrImg, *opts.Quad)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// resize QR to fit “plate/box” area
+		if opts.Width > 0 && opts.Height > 0 {
+			qrImg = imaging.Resize(qrImg, opts.Width, opts.Height, imaging.Lanczos)
+		}
 
-	// Overlay QR on background
-	composite := imaging.Overlay(bg, qrImg, image.Pt(opts.PosX, opts.PosY), 1.0)
+		// Overlay QR on background
+		composite = imaging.Overlay(bg, qrImg, image.Pt(opts.PosX, opts.PosY), 1.0)
+	}
 
 	var out bytes.Buffer
 	if err := png.Encode(&out, composite); err != nil {
@@ -50,3 +78,155 @@ func ComposeQROnBackground(opts CompositeOptions) ([]byte, error) {
 	}
 	return out.Bytes(), nil
 }
+
+// warpOnto draws src onto a copy of bg with its corners on q. Every
+// background pixel inside the quad is mapped back into src through the
+// inverse homography and sampled bilinearly, 2x2 supersampled so the
+// edges stay smooth.
+func warpOnto(bg, src image.Image, q Quad) (*image.NRGBA, error) {
+	h, ok := squareToQuad(q)
+	if !ok {
+		return nil, ErrInvalidQuad
+	}
+	inv, ok := h.inverse()
+	if !ok {
+		return nil, ErrInvalidQuad
+	}
+
+	// Shrink the QR close to its on-screen size first; bilinear sampling
+	// alone would alias the modules when scaling down a lot.
+	side := 0
+	for i := range q {
+		a, b := q[i], q[(i+1)%4]
+		side = max(side, int(math.Ceil(math.Hypot(b.X-a.X, b.Y-a.Y))))
+	}
+	if side > 0 && side < src.Bounds().Dx() {
+		src = imaging.Resize(src, side, 0, imaging.Lanczos)
+	}
+	s := imaging.Clone(src)
+	sw, sh := float64(s.Bounds().Dx()), float64(s.Bounds().Dy())
+
+	dst := imaging.Clone(bg)
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range q {
+		minX, minY = math.Min(minX, c.X), math.Min(minY, c.Y)
+		maxX, maxY = math.Max(maxX, c.X), math.Max(maxY, c.Y)
+	}
+	area := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(dst.Bounds())
+
+	offsets := [4][2]float64{{0.25, 0.25}, {0.75, 0.25}, {0.25, 0.75}, {0.75, 0.75}}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			var r, g, b, a float64
+			for _, o := range offsets {
+				u, v := inv.apply(float64(x)+o[0], float64(y)+o[1])
+				if u < 0 || u > 1 || v < 0 || v > 1 {
+					continue
+				}
+				c := bilinear(s, u*sw-0.5, v*sh-0.5)
+				ca := float64(c.A) / 255
+				r += float64(c.R) * ca
+				g += float64(c.G) * ca
+				b += float64(c.B) * ca
+				a += ca
+			}
+			if a == 0 {
+				continue
+			}
+			// a/4 is coverage; blend the premultiplied sample over bg
+			cover := a / 4
+			under := dst.NRGBAAt(x, y)
+			mix := func(sum float64, base uint8) uint8 {
+				return uint8(math.Round(sum/a*cover + float64(base)*(1-cover)))
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: mix(r, under.R),
+				G: mix(g, under.G),
+				B: mix(b, under.B),
+				A: uint8(math.Round(255*cover + float64(under.A)*(1-cover))),
+			})
+		}
+	}
+	return dst, nil
+}
+
+// bilinear samples img at a fractional pixel position, clamped to the edges
+func bilinear(img *image.NRGBA, x, y float64) color.NRGBA {
+	b := img.Bounds()
+	clampX := func(v int) int { return min(max(v, b.Min.X), b.Max.X-1) }
+	clampY := func(v int) int { return min(max(v, b.Min.Y), b.Max.Y-1) }
+
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+	c00 := img.NRGBAAt(clampX(x0), clampY(y0))
+	c10 := img.NRGBAAt(clampX(x0+1), clampY(y0))
+	c01 := img.NRGBAAt(clampX(x0), clampY(y0+1))
+	c11 := img.NRGBAAt(clampX(x0+1), clampY(y0+1))
+
+	lerp := func(a, b, c, d uint8) uint8 {
+		top := float64(a) + (float64(b)-float64(a))*fx
+		bottom := float64(c) + (float64(d)-float64(c))*fx
+		return uint8(math.Round(top + (bottom-top)*fy))
+	}
+	return color.NRGBA{
+		R: lerp(c00.R, c10.R, c01.R, c11.R),
+		G: lerp(c00.G, c10.G, c01.G, c11.G),
+		B: lerp(c00.B, c10.B, c01.B, c11.B),
+		A: lerp(c00.A, c10.A, c01.A, c11.A),
+	}
+}
+
+// homography is a 3x3 projective transform, row major
+type homography [9]float64
+
+func (m homography) apply(x, y float64) (float64, float64) {
+	w := m[6]*x + m[7]*y + m[8]
+	return (m[0]*x + m[1]*y + m[2]) / w, (m[3]*x + m[4]*y + m[5]) / w
+}
+
+// squareToQuad maps the unit square onto q (Heckbert, "Fundamentals of
+// Texture Mapping and Image Warping", 1989).
+func squareToQuad(q Quad) (homography, bool) {
+	x0, y0 := q[0].X, q[0].Y
+	x1, y1 := q[1].X, q[1].Y
+	x2, y2 := q[2].X, q[2].Y
+	x3, y3 := q[3].X, q[3].Y
+
+	sx := x0 - x1 + x2 - x3
+	sy := y0 - y1 + y2 - y3
+	if sx == 0 && sy == 0 {
+		// Parallelogram, plain affine transform
+		return homography{x1 - x0, x3 - x0, x0, y1 - y0, y3 - y0, y0, 0, 0, 1}, true
+	}
+
+	dx1, dx2 := x1-x2, x3-x2
+	dy1, dy2 := y1-y2, y3-y2
+	den := dx1*dy2 - dx2*dy1
+	if den == 0 {
+		return homography{}, false
+	}
+	g := (sx*dy2 - dx2*sy) / den
+	h := (dx1*sy - sx*dy1) / den
+	return homography{
+		x1 - x0 + g*x1, x3 - x0 + h*x3, x0,
+		y1 - y0 + g*y1, y3 - y0 + h*y3, y0,
+		g, h, 1,
+	}, true
+}
+
+func (m homography) inverse() (homography, bool) {
+	a, b, c := m[0], m[1], m[2]
+	d, e, f := m[3], m[4], m[5]
+	g, h, i := m[6], m[7], m[8]
+
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	if math.Abs(det) < 1e-12 {
+		return homography{}, false
+	}
+	return homography{
+		(e*i - f*h) / det, (c*h - b*i) / det, (b*f - c*e) / det,
+		(f*g - d*i) / det, (a*i - c*g) / det, (c*d - a*f) / det,
+		(d*h - e*g) / det, (b*g - a*h) / det, (a*e - b*d) / det,
+	}, true
+}
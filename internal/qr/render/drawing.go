@@ -0,0 +1,201 @@
+package render
+
+import (
+	"image"
+	"image/color"
+)
+
+// drawing is a resolution independent description of a rendered code.
+// Coordinates are in module units; the raster, SVG and PDF writers scale
+// it to their output size, so every backend draws exactly the same thing.
+type drawing struct {
+	Width      float64
+	Height     float64
+	Background color.RGBA
+	Items      []drawItem
+}
+
+// drawItem is either a filled path or a placed raster image
+type drawItem struct {
+	Path  path
+	Fill  paint
+	Image image.Image
+	Box   rect
+}
+
+type rect struct {
+	X, Y, W, H float64
+}
+
+type point struct {
+	X, Y float64
+}
+
+type opKind int
+
+const (
+	opMove opKind = iota
+	opLine
+	opCube
+	opClose
+)
+
+// pathOp is one path command; C1 and C2 are only used by opCube
+type pathOp struct {
+	Kind   opKind
+	P      point
+	C1, C2 point
+}
+
+// path is a list of subpaths filled with the non-zero winding rule.
+// Holes are drawn by giving the inner subpath the opposite direction.
+type path []pathOp
+
+// kappa places cubic control points so four curves approximate a circle
+const kappa = 0.5522847498
+
+func (p *path) moveTo(x, y float64) { *p = append(*p, pathOp{Kind: opMove, P: point{x, y}}) }
+func (p *path) lineTo(x, y float64) { *p = append(*p, pathOp{Kind: opLine, P: point{x, y}}) }
+func (p *path) close()              { *p = append(*p, pathOp{Kind: opClose}) }
+
+func (p *path) cubeTo(c1x, c1y, c2x, c2y, x, y float64) {
+	*p = append(*p, pathOp{Kind: opCube, P: point{x, y}, C1: point{c1x, c1y}, C2: point{c2x, c2y}})
+}
+
+func (p *path) append(other path) { *p = append(*p, other...) }
+
+// rect adds a clockwise rectangle subpath
+func (p *path) rect(x, y, w, h float64) {
+	p.moveTo(x, y)
+	p.lineTo(x+w, y)
+	p.lineTo(x+w, y+h)
+	p.lineTo(x, y+h)
+	p.close()
+}
+
+// roundedRect adds a clockwise rectangle whose corners (top-left,
+// top-right, bottom-right, bottom-left) have the given radii.
+func (p *path) roundedRect(x, y, w, h float64, r [4]float64) {
+	k := 1 - kappa
+	tl, tr, br, bl := r[0], r[1], r[2], r[3]
+
+	p.moveTo(x+tl, y)
+	p.lineTo(x+w-tr, y)
+	if tr > 0 {
+		p.cubeTo(x+w-tr*k, y, x+w, y+tr*k, x+w, y+tr)
+	}
+	p.lineTo(x+w, y+h-br)
+	if br > 0 {
+		p.cubeTo(x+w, y+h-br*k, x+w-br*k, y+h, x+w-br, y+h)
+	}
+	p.lineTo(x+bl, y+h)
+	if bl > 0 {
+		p.cubeTo(x+bl*k, y+h, x, y+h-bl*k, x, y+h-bl)
+	}
+	p.lineTo(x, y+tl)
+	if tl > 0 {
+		p.cubeTo(x, y+tl*k, x+tl*k, y, x+tl, y)
+	}
+	p.close()
+}
+
+// circle adds a clockwise circle subpath
+func (p *path) circle(cx, cy, r float64) {
+	c := r * kappa
+	p.moveTo(cx, cy-r)
+	p.cubeTo(cx+c, cy-r, cx+r, cy-c, cx+r, cy)
+	p.cubeTo(cx+r, cy+c, cx+c, cy+r, cx, cy+r)
+	p.cubeTo(cx-c, cy+r, cx-r, cy+c, cx-r, cy)
+	p.cubeTo(cx-r, cy-c, cx-c, cy-r, cx, cy-r)
+	p.close()
+}
+
+// reversed returns the path with every subpath running the other way,
+// turning a filled shape into a hole when appended to its outline.
+func (p path) reversed() path {
+	var out path
+	start := 0
+	for start < len(p) {
+		end := start + 1
+		for end < len(p) && p[end].Kind != opMove {
+			end++
+		}
+		out = append(out, reverseSubpath(p[start:end])...)
+		start = end
+	}
+	return out
+}
+
+func reverseSubpath(sp path) path {
+	closed := len(sp) > 0 && sp[len(sp)-1].Kind == opClose
+	if closed {
+		sp = sp[:len(sp)-1]
+	}
+	if len(sp) == 0 {
+		return nil
+	}
+
+	var out path
+	last := sp[len(sp)-1].P
+	out.moveTo(last.X, last.Y)
+	for i := len(sp) - 1; i > 0; i-- {
+		prev := sp[i-1].P
+		switch sp[i].Kind {
+		case opCube:
+			out.cubeTo(sp[i].C2.X, sp[i].C2.Y, sp[i].C1.X, sp[i].C1.Y, prev.X, prev.Y)
+		default:
+			out.lineTo(prev.X, prev.Y)
+		}
+	}
+	if closed {
+		out.close()
+	}
+	return out
+}
+
+func (d *drawing) fill(p path, f paint) {
+	if len(p) == 0 {
+		return
+	}
+	d.Items = append(d.Items, drawItem{Path: p, Fill: f})
+}
+
+func (d *drawing) image(img image.Image, box rect) {
+	d.Items = append(d.Items, drawItem{Image: img, Box: box})
+}
+
+// translated returns the drawing's items moved by (dx, dy). Gradients
+// are copied once each so items sharing one still share the copy.
+func (d *drawing) translated(dx, dy float64) []drawItem {
+	moved := map[*gradientFill]*gradientFill{}
+	items := make([]drawItem, 0, len(d.Items))
+	for _, it := range d.Items {
+		it.Box.X += dx
+		it.Box.Y += dy
+		if it.Path != nil {
+			it.Path = it.Path.translated(dx, dy)
+		}
+		if g := it.Fill.Gradient; g != nil {
+			if _, ok := moved[g]; !ok {
+				cp := *g
+				cp.X0, cp.Y0 = g.X0+dx, g.Y0+dy
+				cp.X1, cp.Y1 = g.X1+dx, g.Y1+dy
+				moved[g] = &cp
+			}
+			it.Fill.Gradient = moved[g]
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+func (p path) translated(dx, dy float64) path {
+	out := make(path, len(p))
+	for i, op := range p {
+		op.P = point{op.P.X + dx, op.P.Y + dy}
+		op.C1 = point{op.C1.X + dx, op.C1.Y + dy}
+		op.C2 = point{op.C2.X + dx, op.C2.Y + dy}
+		out[i] = op
+	}
+	return out
+}
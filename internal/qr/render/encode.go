@@ -0,0 +1,93 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// jpegQuality keeps module edges crisp; QR codes compress well anyway
+const jpegQuality = 92
+
+// encodeRaster writes img as png, jpeg or webp. A non-zero dpi is stored
+// in the file (PNG pHYs, JPEG JFIF density) so print tools pick up the
+// intended physical size; WebP has no standard field for it.
+func encodeRaster(img image.Image, format string, dpi int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case FormatJPEG:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		if dpi > 0 {
+			return withJFIFDensity(buf.Bytes(), dpi), nil
+		}
+		return buf.Bytes(), nil
+	case FormatWebP:
+		if err := nativewebp.Encode(&buf, img, nil); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	if dpi > 0 {
+		return withPHYs(buf.Bytes(), dpi), nil
+	}
+	return buf.Bytes(), nil
+}
+
+// withPHYs inserts a pHYs chunk (pixels per meter) right after IHDR,
+// which is where the PNG spec wants it: before the first IDAT.
+func withPHYs(data []byte, dpi int) []byte {
+	// 8 byte signature + IHDR (4 length + 4 type + 13 data + 4 crc)
+	const afterIHDR = 8 + 4 + 4 + 13 + 4
+	if len(data) < afterIHDR {
+		return data
+	}
+
+	ppm := uint32(math.Round(float64(dpi) / 0.0254))
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk[0:], 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], ppm)
+	binary.BigEndian.PutUint32(chunk[12:], ppm)
+	chunk[16] = 1 // unit: meter
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:afterIHDR]...)
+	out = append(out, chunk...)
+	return append(out, data[afterIHDR:]...)
+}
+
+// withJFIFDensity adds a JFIF APP0 segment with the density in dots per
+// inch after SOI. Go's encoder doesn't write one.
+func withJFIFDensity(data []byte, dpi int) []byte {
+	if len(data) < 2 {
+		return data
+	}
+	d := uint16(min(dpi, math.MaxUint16))
+	app0 := []byte{
+		0xFF, 0xE0, 0x00, 0x10, // APP0, length 16
+		'J', 'F', 'I', 'F', 0x00,
+		0x01, 0x02, // version 1.02
+		0x01, // units: dots per inch
+		byte(d >> 8), byte(d), byte(d >> 8), byte(d),
+		0x00, 0x00, // no thumbnail
+	}
+
+	out := make([]byte, 0, len(data)+len(app0))
+	out = append(out, data[:2]...)
+	out = append(out, app0...)
+	return append(out, data[2:]...)
+}
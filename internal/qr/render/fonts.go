@@ -0,0 +1,199 @@
+package render
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/gofont/gosmallcaps"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultFont is used when a frame or caption doesn't pick one
+const DefaultFont = "go-bold"
+
+var ErrUnknownFont = errors.New("unknown font")
+
+// bundledFonts are compiled into the binary so rendering never depends
+// on what's installed on the host.
+var bundledFonts = map[string][]byte{
+	"go-regular":   goregular.TTF,
+	"go-medium":    gomedium.TTF,
+	"go-bold":      gobold.TTF,
+	"go-italic":    goitalic.TTF,
+	"go-smallcaps": gosmallcaps.TTF,
+	"go-mono":      gomono.TTF,
+	"go-mono-bold": gomonobold.TTF,
+}
+
+var (
+	fontMu     sync.Mutex
+	fontParsed = map[string]*sfnt.Font{}
+)
+
+// FontNames lists the bundled fonts a design can reference
+func FontNames() []string {
+	names := make([]string, 0, len(bundledFonts))
+	for name := range bundledFonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadFont parses a bundled font once and caches it; empty selects
+// DefaultFont.
+func loadFont(name string) (*sfnt.Font, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultFont
+	}
+
+	fontMu.Lock()
+	defer fontMu.Unlock()
+
+	if f, ok := fontParsed[name]; ok {
+		return f, nil
+	}
+	data, ok := bundledFonts[name]
+	if !ok {
+		return nil, ErrUnknownFont
+	}
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	fontParsed[name] = f
+	return f, nil
+}
+
+// textPPEM is the resolution glyphs are loaded at before being scaled
+// to drawing units; high enough that rounding to 26.6 is invisible.
+const textPPEM = 1024
+
+// textLine is a single line of text ready to be turned into outlines
+type textLine struct {
+	font *sfnt.Font
+	text string
+	size float64 // em size in drawing units
+}
+
+func (t textLine) scale() float64 {
+	return t.size / textPPEM
+}
+
+// width is the advance width of the whole line, kerning included
+func (t textLine) width() float64 {
+	var buf sfnt.Buffer
+	ppem := fixed.I(textPPEM)
+	var total fixed.Int26_6
+	prev := sfnt.GlyphIndex(0)
+
+	for i, r := range t.text {
+		idx, err := t.font.GlyphIndex(&buf, r)
+		if err != nil {
+			continue
+		}
+		if i > 0 && prev != 0 {
+			if k, err := t.font.Kern(&buf, prev, idx, ppem, font.HintingNone); err == nil {
+				total += k
+			}
+		}
+		adv, err := t.font.GlyphAdvance(&buf, idx, ppem, font.HintingNone)
+		if err == nil {
+			total += adv
+		}
+		prev = idx
+	}
+	return fixedToFloat(total) * t.scale()
+}
+
+// capHeight is the height of uppercase letters above the baseline,
+// used to center labels optically.
+func (t textLine) capHeight() float64 {
+	var buf sfnt.Buffer
+	m, err := t.font.Metrics(&buf, fixed.I(textPPEM), font.HintingNone)
+	if err != nil || m.CapHeight <= 0 {
+		return t.size * 0.7
+	}
+	return fixedToFloat(m.CapHeight) * t.scale()
+}
+
+// outline converts the glyphs to a path with the baseline starting at
+// (x, y). TrueType contours already wind in opposite directions for
+// holes, so the non-zero fill rule renders counters correctly.
+func (t textLine) outline(x, y float64) path {
+	var buf sfnt.Buffer
+	var p path
+	ppem := fixed.I(textPPEM)
+	s := t.scale()
+	pen := x
+	prev := sfnt.GlyphIndex(0)
+
+	for i, r := range t.text {
+		idx, err := t.font.GlyphIndex(&buf, r)
+		if err != nil {
+			continue
+		}
+		if i > 0 && prev != 0 {
+			if k, err := t.font.Kern(&buf, prev, idx, ppem, font.HintingNone); err == nil {
+				pen += fixedToFloat(k) * s
+			}
+		}
+
+		segs, err := t.font.LoadGlyph(&buf, idx, ppem, nil)
+		if err == nil {
+			var cur point
+			for _, seg := range segs {
+				pt := func(j int) point {
+					return point{pen + fixedToFloat(seg.Args[j].X)*s, y + fixedToFloat(seg.Args[j].Y)*s}
+				}
+				switch seg.Op {
+				case sfnt.SegmentOpMoveTo:
+					if len(p) > 0 && p[len(p)-1].Kind != opClose {
+						p.close()
+					}
+					cur = pt(0)
+					p.moveTo(cur.X, cur.Y)
+				case sfnt.SegmentOpLineTo:
+					cur = pt(0)
+					p.lineTo(cur.X, cur.Y)
+				case sfnt.SegmentOpQuadTo:
+					// Elevate to a cubic so every backend only needs one curve type
+					q, end := pt(0), pt(1)
+					p.cubeTo(
+						cur.X+2.0/3*(q.X-cur.X), cur.Y+2.0/3*(q.Y-cur.Y),
+						end.X+2.0/3*(q.X-end.X), end.Y+2.0/3*(q.Y-end.Y),
+						end.X, end.Y)
+					cur = end
+				case sfnt.SegmentOpCubeTo:
+					c1, c2, end := pt(0), pt(1), pt(2)
+					p.cubeTo(c1.X, c1.Y, c2.X, c2.Y, end.X, end.Y)
+					cur = end
+				}
+			}
+			if len(p) > 0 && p[len(p)-1].Kind != opClose {
+				p.close()
+			}
+		}
+
+		if adv, err := t.font.GlyphAdvance(&buf, idx, ppem, font.HintingNone); err == nil {
+			pen += fixedToFloat(adv) * s
+		}
+		prev = idx
+	}
+	return p
+}
+
+func fixedToFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
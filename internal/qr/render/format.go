@@ -0,0 +1,54 @@
+package render
+
+import (
+	"errors"
+	"strings"
+)
+
+// Output formats supported by RenderQRWithLogo
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+	FormatSVG  = "svg"
+	FormatPDF  = "pdf"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+var contentTypes = map[string]string{
+	FormatPNG:  "image/png",
+	FormatJPEG: "image/jpeg",
+	FormatWebP: "image/webp",
+	FormatSVG:  "image/svg+xml",
+	FormatPDF:  "application/pdf",
+}
+
+// NormalizeFormat lower-cases the format and defaults empty values to PNG.
+// It returns ErrUnsupportedFormat for anything we cannot render.
+func NormalizeFormat(format string) (string, error) {
+	f := strings.ToLower(strings.TrimSpace(format))
+	if f == "" {
+		return FormatPNG, nil
+	}
+	if f == "jpg" {
+		return FormatJPEG, nil
+	}
+	if _, ok := contentTypes[f]; !ok {
+		return "", ErrUnsupportedFormat
+	}
+	return f, nil
+}
+
+// ContentType returns the MIME type for a (normalized) format
+func ContentType(format string) string {
+	if ct, ok := contentTypes[format]; ok {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// IsVector reports whether the format is resolution independent
+func IsVector(format string) bool {
+	return format == FormatSVG || format == FormatPDF
+}
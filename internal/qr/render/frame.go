@@ -0,0 +1,165 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Frame draws a border or banner around the code carrying a short
+// call-to-action label such as "SCAN ME".
+type Frame struct {
+	Style     string `json:"style"`     // border (default) or banner
+	Position  string `json:"position"`  // bottom (default), top or badge
+	Text      string `json:"text"`      // defaults to DefaultFrameText
+	Font      string `json:"font"`      // one of FontNames(), defaults to DefaultFont
+	Color     string `json:"color"`     // Hex code, defaults to the code color
+	TextColor string `json:"textColor"` // Hex code, defaults to black or white, whichever reads better
+}
+
+const (
+	FrameBorder = "border"
+	FrameBanner = "banner"
+
+	FrameBottom = "bottom"
+	FrameTop    = "top"
+	FrameBadge  = "badge" // pill overlapping the bottom edge
+)
+
+const (
+	DefaultFrameText = "SCAN ME"
+	MaxFrameText     = 32 // characters
+)
+
+var ErrInvalidFrame = fmt.Errorf("frame needs style border or banner, position top, bottom or badge and at most %d characters of text", MaxFrameText)
+
+// Frame proportions, relative to the shorter side of the code (quiet zone
+// included)
+const (
+	frameBorderWidth = 0.05
+	frameLabelHeight = 0.2
+	frameBadgeWidth  = 0.6
+	frameTextSize    = 0.55 // of the label height
+)
+
+// applyFrame places the code drawing inside a frame and returns the new,
+// taller drawing. Size stays the output width, so the code shrinks a bit
+// to make room for the label.
+func applyFrame(code *drawing, f *Frame, st codeStyle) (*drawing, error) {
+	style, err := pickShape(f.Style, FrameBorder, FrameBanner)
+	if err != nil {
+		return nil, ErrInvalidFrame
+	}
+	position, err := pickShape(f.Position, FrameBottom, FrameTop, FrameBadge)
+	if err != nil {
+		return nil, ErrInvalidFrame
+	}
+
+	text := strings.TrimSpace(f.Text)
+	if text == "" {
+		text = DefaultFrameText
+	}
+	if utf8.RuneCountInString(text) > MaxFrameText {
+		return nil, ErrInvalidFrame
+	}
+
+	font, err := loadFont(f.Font)
+	if err != nil {
+		return nil, err
+	}
+
+	frameColor := st.Foreground
+	if f.Color != "" {
+		frameColor = parseHexColor(f.Color)
+	}
+	textColor := readableOn(frameColor)
+	if f.TextColor != "" {
+		textColor = parseHexColor(f.TextColor)
+	}
+
+	// Linear and PDF417 symbols are wider than tall
+	cw, ch := code.Width, code.Height
+	s := math.Min(cw, ch)
+	label := s * frameLabelHeight
+	var b float64
+	if style == FrameBorder {
+		b = s * frameBorderWidth
+	}
+
+	w := cw + 2*b
+	codeX, codeY := b, b
+	var h float64
+	var band rect // where the label text is centered
+	var frame path
+
+	switch position {
+	case FrameTop, FrameBottom:
+		h = ch + 2*b + label
+		if position == FrameTop {
+			codeY = b + label
+			band = rect{X: b, Y: b, W: cw, H: label}
+		} else {
+			band = rect{X: b, Y: b + ch, W: cw, H: label}
+		}
+
+		r := label / 3
+		if style == FrameBorder {
+			frame.roundedRect(0, 0, w, h, [4]float64{r, r, r, r})
+			var hole path
+			hole.rect(codeX, codeY, cw, ch)
+			frame.append(hole.reversed())
+		} else if position == FrameTop {
+			frame.roundedRect(band.X, band.Y, band.W, band.H, [4]float64{r, r, 0, 0})
+		} else {
+			frame.roundedRect(band.X, band.Y, band.W, band.H, [4]float64{0, 0, r, r})
+		}
+
+	case FrameBadge:
+		pillH := label * 0.85
+		pillW := s * frameBadgeWidth
+		h = ch + 2*b + pillH/2
+		if style == FrameBorder {
+			frame.rect(0, 0, w, ch+2*b)
+			var hole path
+			hole.rect(codeX, codeY, cw, ch)
+			frame.append(hole.reversed())
+		}
+		band = rect{X: (w - pillW) / 2, Y: ch + 2*b - pillH/2, W: pillW, H: pillH}
+		r := pillH / 2
+		frame.roundedRect(band.X, band.Y, band.W, band.H, [4]float64{r, r, r, r})
+		// Keep the text clear of the rounded ends
+		band.X += r / 2
+		band.W -= r
+	}
+
+	d := &drawing{Width: w, Height: h, Background: code.Background}
+	d.Items = code.translated(codeX, codeY)
+	d.fill(frame, solid(frameColor))
+
+	line := textLine{font: font, text: text, size: band.H * frameTextSize}
+	if tw, limit := line.width(), band.W*0.9; tw > limit {
+		line.size *= limit / tw
+	}
+	x := band.X + (band.W-line.width())/2
+	baseline := band.Y + (band.H+line.capHeight())/2
+	d.fill(line.outline(x, baseline), solid(textColor))
+
+	return d, nil
+}
+
+// readableOn picks black or white text, whichever contrasts more with bg
+func readableOn(bg color.RGBA) color.RGBA {
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	black := color.RGBA{A: 0xff}
+	if contrastRatio(white, bg) >= contrastRatio(black, bg) {
+		return white
+	}
+	return black
+}
+
+// outputSize scales the drawing to width px, keeping its aspect ratio
+func outputSize(d *drawing, width int) (int, int) {
+	return width, int(math.Round(float64(width) * d.Height / d.Width))
+}
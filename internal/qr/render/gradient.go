@@ -0,0 +1,234 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Gradient fills the modules with a linear or radial color ramp
+type Gradient struct {
+	Type  string         `json:"type"`  // linear (default) or radial
+	Angle float64        `json:"angle"` // linear only, degrees: 0 = left to right, 90 = top to bottom
+	Stops []GradientStop `json:"stops"`
+}
+
+type GradientStop struct {
+	Offset float64 `json:"offset"` // 0..1 along the gradient
+	Color  string  `json:"color"`  // Hex code
+}
+
+const (
+	GradientLinear = "linear"
+	GradientRadial = "radial"
+)
+
+// Contrast thresholds (WCAG ratio) between the foreground and BgColor.
+// Below MinContrast gradients are refused; below RecommendedContrast
+// cheaper phone cameras start to struggle, so we only warn.
+const (
+	MinContrast         = 2.0
+	RecommendedContrast = 3.0
+)
+
+var (
+	ErrInvalidGradient = errors.New("gradient needs type linear or radial and 2+ stops with offsets between 0 and 1")
+	ErrLowContrast     = errors.New("foreground contrast against the background is too low to scan")
+)
+
+type colorStop struct {
+	Offset float64
+	Color  color.RGBA
+}
+
+// gradientFill is a gradient resolved against drawing coordinates.
+// Linear gradients run from (X0,Y0) to (X1,Y1); radial ones grow from
+// (X0,Y0) out to radius R.
+type gradientFill struct {
+	Radial bool
+	X0, Y0 float64
+	X1, Y1 float64
+	R      float64
+	Stops  []colorStop
+}
+
+// paint is a solid color or, when Gradient is set, a gradient
+type paint struct {
+	Color    color.RGBA
+	Gradient *gradientFill
+}
+
+func solid(c color.RGBA) paint {
+	return paint{Color: c}
+}
+
+// colorStops validates and sorts the stops, padding them so the ramp
+// always covers 0..1.
+func (g *Gradient) colorStops() ([]colorStop, error) {
+	t := strings.ToLower(strings.TrimSpace(g.Type))
+	if t != "" && t != GradientLinear && t != GradientRadial {
+		return nil, ErrInvalidGradient
+	}
+	if len(g.Stops) < 2 {
+		return nil, ErrInvalidGradient
+	}
+
+	stops := make([]colorStop, 0, len(g.Stops)+2)
+	for _, s := range g.Stops {
+		if s.Offset < 0 || s.Offset > 1 || s.Color == "" {
+			return nil, ErrInvalidGradient
+		}
+		stops = append(stops, colorStop{Offset: s.Offset, Color: parseHexColor(s.Color)})
+	}
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].Offset < stops[j].Offset })
+
+	if stops[0].Offset > 0 {
+		stops = append([]colorStop{{Offset: 0, Color: stops[0].Color}}, stops...)
+	}
+	if last := stops[len(stops)-1]; last.Offset < 1 {
+		stops = append(stops, colorStop{Offset: 1, Color: last.Color})
+	}
+	return stops, nil
+}
+
+// resolve places the gradient over box (the code area, quiet zone
+// excluded) so the first and last stops land on its edges.
+func (g *Gradient) resolve(stops []colorStop, box rect) *gradientFill {
+	cx := box.X + box.W/2
+	cy := box.Y + box.H/2
+
+	if strings.EqualFold(strings.TrimSpace(g.Type), GradientRadial) {
+		return &gradientFill{
+			Radial: true,
+			X0:     cx,
+			Y0:     cy,
+			R:      math.Hypot(box.W, box.H) / 2,
+			Stops:  stops,
+		}
+	}
+
+	rad := g.Angle * math.Pi / 180
+	dx, dy := math.Cos(rad), math.Sin(rad)
+	half := (math.Abs(box.W*dx) + math.Abs(box.H*dy)) / 2
+	return &gradientFill{
+		X0:    cx - dx*half,
+		Y0:    cy - dy*half,
+		X1:    cx + dx*half,
+		Y1:    cy + dy*half,
+		Stops: stops,
+	}
+}
+
+// at returns the gradient color at (x, y) in drawing coordinates
+func (g *gradientFill) at(x, y float64) color.RGBA {
+	var t float64
+	if g.Radial {
+		if g.R > 0 {
+			t = math.Hypot(x-g.X0, y-g.Y0) / g.R
+		}
+	} else {
+		vx, vy := g.X1-g.X0, g.Y1-g.Y0
+		if l2 := vx*vx + vy*vy; l2 > 0 {
+			t = ((x-g.X0)*vx + (y-g.Y0)*vy) / l2
+		}
+	}
+	t = math.Max(0, math.Min(1, t))
+
+	for i := 1; i < len(g.Stops); i++ {
+		a, b := g.Stops[i-1], g.Stops[i]
+		if t > b.Offset {
+			continue
+		}
+		span := b.Offset - a.Offset
+		if span <= 0 {
+			return b.Color
+		}
+		return lerpColor(a.Color, b.Color, (t-a.Offset)/span)
+	}
+	return g.Stops[len(g.Stops)-1].Color
+}
+
+func lerpColor(a, b color.RGBA, f float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*f))
+	}
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
+
+// gradientImage adapts a gradient to image.Image for the rasterizer;
+// sx and sy convert pixels back to drawing coordinates.
+type gradientImage struct {
+	g      *gradientFill
+	sx, sy float64
+	bounds image.Rectangle
+}
+
+func (gi *gradientImage) ColorModel() color.Model { return color.RGBAModel }
+func (gi *gradientImage) Bounds() image.Rectangle { return gi.bounds }
+func (gi *gradientImage) At(x, y int) color.Color {
+	return gi.g.at((float64(x)+0.5)/gi.sx, (float64(y)+0.5)/gi.sy)
+}
+
+// CheckContrast compares the foreground (every gradient stop, or the
+// flat color) against the background. Gradients below MinContrast are
+// refused with ErrLowContrast; anything under RecommendedContrast comes
+// back as a warning.
+func CheckContrast(opts RenderOptions) ([]string, error) {
+	bg := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if opts.BackgroundColor != "" {
+		bg = parseHexColor(opts.BackgroundColor)
+	}
+
+	var warnings []string
+
+	if opts.Gradient != nil {
+		stops, err := opts.Gradient.colorStops()
+		if err != nil {
+			return nil, err
+		}
+		worst := math.Inf(1)
+		for _, s := range stops {
+			worst = math.Min(worst, contrastRatio(s.Color, bg))
+		}
+		if worst < MinContrast {
+			return nil, fmt.Errorf("%w (gradient %.1f:1, need at least %.1f:1)", ErrLowContrast, worst, MinContrast)
+		}
+		if worst < RecommendedContrast {
+			warnings = append(warnings, fmt.Sprintf("gradient contrast is only %.1f:1, %.1f:1 or more scans more reliably", worst, RecommendedContrast))
+		}
+		return warnings, nil
+	}
+
+	fg := color.RGBA{A: 0xff}
+	if opts.Color != "" {
+		fg = parseHexColor(opts.Color)
+	}
+	if ratio := contrastRatio(fg, bg); ratio < RecommendedContrast {
+		warnings = append(warnings, fmt.Sprintf("foreground contrast is only %.1f:1, %.1f:1 or more scans more reliably", ratio, RecommendedContrast))
+	}
+	return warnings, nil
+}
+
+// contrastRatio is the WCAG 2 contrast ratio between two colors (1..21)
+func contrastRatio(a, b color.RGBA) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+func relativeLuminance(c color.RGBA) float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
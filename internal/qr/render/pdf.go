@@ -0,0 +1,362 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pdfDoc is a minimal PDF 1.4 writer: vector paths, solid and gradient
+// fills and RGBA images are all we need for print output, so we avoid
+// pulling in a full PDF library.
+type pdfDoc struct {
+	objects  [][]byte // object N is objects[N-1]
+	pages    []*pdfPage
+	images   map[image.Image]int   // image -> XObject number, shared across pages
+	shadings map[*gradientFill]int // gradient -> shading number
+}
+
+type pdfPage struct {
+	doc      *pdfDoc
+	width    float64 // points
+	height   float64
+	content  bytes.Buffer
+	xobjects map[string]int
+	shadings map[string]int
+	states   map[string]float64 // ExtGState name -> fill alpha
+}
+
+const (
+	pdfCatalogObj = 1
+	pdfPagesObj   = 2
+)
+
+func newPDF() *pdfDoc {
+	doc := &pdfDoc{images: map[image.Image]int{}, shadings: map[*gradientFill]int{}}
+	doc.alloc() // catalog, written in bytes()
+	doc.alloc() // page tree
+	return doc
+}
+
+func (doc *pdfDoc) alloc() int {
+	doc.objects = append(doc.objects, nil)
+	return len(doc.objects)
+}
+
+func (doc *pdfDoc) set(num int, body []byte) {
+	doc.objects[num-1] = body
+}
+
+// addPage appends a page of w x h points
+func (doc *pdfDoc) addPage(w, h float64) *pdfPage {
+	pg := &pdfPage{
+		doc:      doc,
+		width:    w,
+		height:   h,
+		xobjects: map[string]int{},
+		shadings: map[string]int{},
+		states:   map[string]float64{},
+	}
+	doc.pages = append(doc.pages, pg)
+	return pg
+}
+
+// drawDrawing places d into the box (x, y, w, h) given in points from
+// the top-left corner of the page.
+func (pg *pdfPage) drawDrawing(d *drawing, x, y, w, h float64) error {
+	sx := w / d.Width
+	sy := h / d.Height
+
+	c := &pg.content
+	c.WriteString("q\n")
+	// Flip to a y-down coordinate system in module units
+	fmt.Fprintf(c, "%s 0 0 %s %s %s cm\n", pdfNum(sx), pdfNum(-sy), pdfNum(x), pdfNum(pg.height-y))
+
+	pg.setFill(d.Background)
+	fmt.Fprintf(c, "0 0 %s %s re f\n", pdfNum(d.Width), pdfNum(d.Height))
+
+	for _, it := range d.Items {
+		if it.Image != nil {
+			name, err := pg.useImage(it.Image)
+			if err != nil {
+				return err
+			}
+			b := it.Box
+			fmt.Fprintf(c, "q %s 0 0 %s %s %s cm /%s Do Q\n",
+				pdfNum(b.W), pdfNum(-b.H), pdfNum(b.X), pdfNum(b.Y+b.H), name)
+			continue
+		}
+
+		if g := it.Fill.Gradient; g != nil {
+			// Clip to the path, then paint the shading through it
+			c.WriteString("q\n")
+			pg.setAlpha(0xff)
+			writePDFPath(c, it.Path)
+			fmt.Fprintf(c, "W n /%s sh\nQ\n", pg.useShading(g))
+			continue
+		}
+
+		pg.setFill(it.Fill.Color)
+		writePDFPath(c, it.Path)
+		c.WriteString("f\n")
+	}
+
+	c.WriteString("Q\n")
+	return nil
+}
+
+// strokeRect outlines the box (x, y, w, h), in points from the top-left
+// corner of the page, with a line of width points
+func (pg *pdfPage) strokeRect(x, y, w, h, width float64, col color.RGBA) {
+	c := &pg.content
+	pg.setAlpha(col.A)
+	fmt.Fprintf(c, "%s %s %s RG %s w\n",
+		pdfNum(float64(col.R)/255), pdfNum(float64(col.G)/255), pdfNum(float64(col.B)/255), pdfNum(width))
+	fmt.Fprintf(c, "%s %s %s %s re S\n", pdfNum(x), pdfNum(pg.height-y-h), pdfNum(w), pdfNum(h))
+}
+
+func (pg *pdfPage) setFill(col color.RGBA) {
+	pg.setAlpha(col.A)
+	fmt.Fprintf(&pg.content, "%s %s %s rg\n",
+		pdfNum(float64(col.R)/255), pdfNum(float64(col.G)/255), pdfNum(float64(col.B)/255))
+}
+
+func (pg *pdfPage) setAlpha(a uint8) {
+	name := "GS" + strconv.Itoa(int(a))
+	pg.states[name] = float64(a) / 255
+	fmt.Fprintf(&pg.content, "/%s gs\n", name)
+}
+
+func writePDFPath(c *bytes.Buffer, p path) {
+	for _, op := range p {
+		switch op.Kind {
+		case opMove:
+			fmt.Fprintf(c, "%s %s m\n", pdfNum(op.P.X), pdfNum(op.P.Y))
+		case opLine:
+			fmt.Fprintf(c, "%s %s l\n", pdfNum(op.P.X), pdfNum(op.P.Y))
+		case opCube:
+			fmt.Fprintf(c, "%s %s %s %s %s %s c\n",
+				pdfNum(op.C1.X), pdfNum(op.C1.Y), pdfNum(op.C2.X), pdfNum(op.C2.Y), pdfNum(op.P.X), pdfNum(op.P.Y))
+		case opClose:
+			c.WriteString("h\n")
+		}
+	}
+}
+
+// useImage registers img as an XObject (once per document) and returns
+// its resource name on this page.
+func (pg *pdfPage) useImage(img image.Image) (string, error) {
+	num, ok := pg.doc.images[img]
+	if !ok {
+		var err error
+		num, err = pg.doc.addImage(img)
+		if err != nil {
+			return "", err
+		}
+		pg.doc.images[img] = num
+	}
+
+	name := "Im" + strconv.Itoa(num)
+	pg.xobjects[name] = num
+	return name, nil
+}
+
+// useShading registers g as a shading (once per document) and returns
+// its resource name on this page.
+func (pg *pdfPage) useShading(g *gradientFill) string {
+	num, ok := pg.doc.shadings[g]
+	if !ok {
+		num = pg.doc.alloc()
+		pg.doc.set(num, []byte(pdfShading(g)))
+		pg.doc.shadings[g] = num
+	}
+
+	name := "Sh" + strconv.Itoa(num)
+	pg.shadings[name] = num
+	return name
+}
+
+// pdfShading builds an axial (type 2) or radial (type 3) shading whose
+// color function stitches one linear segment per pair of stops.
+func pdfShading(g *gradientFill) string {
+	rgb := func(c color.RGBA) string {
+		return fmt.Sprintf("[%s %s %s]", pdfNum(float64(c.R)/255), pdfNum(float64(c.G)/255), pdfNum(float64(c.B)/255))
+	}
+
+	var funcs, bounds, encode []string
+	for i := 1; i < len(g.Stops); i++ {
+		funcs = append(funcs, fmt.Sprintf("<< /FunctionType 2 /Domain [0 1] /C0 %s /C1 %s /N 1 >>",
+			rgb(g.Stops[i-1].Color), rgb(g.Stops[i].Color)))
+		encode = append(encode, "0 1")
+		if i < len(g.Stops)-1 {
+			bounds = append(bounds, pdfNum(g.Stops[i].Offset))
+		}
+	}
+	fn := fmt.Sprintf("<< /FunctionType 3 /Domain [0 1] /Functions [%s] /Bounds [%s] /Encode [%s] >>",
+		strings.Join(funcs, " "), strings.Join(bounds, " "), strings.Join(encode, " "))
+
+	if g.Radial {
+		return fmt.Sprintf("<< /ShadingType 3 /ColorSpace /DeviceRGB /Coords [%s %s 0 %s %s %s] /Function %s /Extend [true true] >>",
+			pdfNum(g.X0), pdfNum(g.Y0), pdfNum(g.X0), pdfNum(g.Y0), pdfNum(g.R), fn)
+	}
+	return fmt.Sprintf("<< /ShadingType 2 /ColorSpace /DeviceRGB /Coords [%s %s %s %s] /Function %s /Extend [true true] >>",
+		pdfNum(g.X0), pdfNum(g.Y0), pdfNum(g.X1), pdfNum(g.Y1), fn)
+}
+
+func (doc *pdfDoc) addImage(img image.Image) (int, error) {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+		}
+	}
+
+	maskData, err := deflate(alpha)
+	if err != nil {
+		return 0, err
+	}
+	mask := doc.alloc()
+	doc.set(mask, pdfStream(fmt.Sprintf(
+		"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode",
+		b.Dx(), b.Dy()), maskData))
+
+	rgbData, err := deflate(rgb)
+	if err != nil {
+		return 0, err
+	}
+	num := doc.alloc()
+	doc.set(num, pdfStream(fmt.Sprintf(
+		"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /SMask %d 0 R",
+		b.Dx(), b.Dy(), mask), rgbData))
+
+	return num, nil
+}
+
+// bytes serializes the document
+func (doc *pdfDoc) bytes() ([]byte, error) {
+	kids := make([]string, 0, len(doc.pages))
+	for _, pg := range doc.pages {
+		content, err := deflate(pg.content.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		contentNum := doc.alloc()
+		doc.set(contentNum, pdfStream("/Filter /FlateDecode", content))
+
+		pageNum := doc.alloc()
+		doc.set(pageNum, []byte(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			pdfPagesObj, pdfNum(pg.width), pdfNum(pg.height), pg.resources(), contentNum)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageNum))
+	}
+
+	doc.set(pdfCatalogObj, []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObj)))
+	doc.set(pdfPagesObj, []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(kids))))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(doc.objects))
+	for i, body := range doc.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n", len(doc.objects)+1)
+	out.WriteString("0000000000 65535 f \n")
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(doc.objects)+1, pdfCatalogObj, xref)
+
+	return out.Bytes(), nil
+}
+
+func (pg *pdfPage) resources() string {
+	var b strings.Builder
+	b.WriteString("<<")
+
+	if len(pg.xobjects) > 0 {
+		b.WriteString(" /XObject <<")
+		for _, name := range sortedKeys(pg.xobjects) {
+			fmt.Fprintf(&b, " /%s %d 0 R", name, pg.xobjects[name])
+		}
+		b.WriteString(" >>")
+	}
+
+	if len(pg.shadings) > 0 {
+		b.WriteString(" /Shading <<")
+		for _, name := range sortedKeys(pg.shadings) {
+			fmt.Fprintf(&b, " /%s %d 0 R", name, pg.shadings[name])
+		}
+		b.WriteString(" >>")
+	}
+
+	if len(pg.states) > 0 {
+		b.WriteString(" /ExtGState <<")
+		for _, name := range sortedKeys(pg.states) {
+			fmt.Fprintf(&b, " /%s << /ca %s >>", name, pdfNum(pg.states[name]))
+		}
+		b.WriteString(" >>")
+	}
+
+	b.WriteString(" >>")
+	return b.String()
+}
+
+func pdfStream(dict string, data []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	b.Write(data)
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func pdfNum(v float64) string {
+	return svgNum(v)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encodePDF writes the drawing as a single-page PDF of widthPt x heightPt
+func encodePDF(d *drawing, widthPt, heightPt float64) ([]byte, error) {
+	doc := newPDF()
+	pg := doc.addPage(widthPt, heightPt)
+	if err := pg.drawDrawing(d, 0, 0, widthPt, heightPt); err != nil {
+		return nil, err
+	}
+	return doc.bytes()
+}
@@ -1,76 +1,199 @@
 package render
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
 	"os"
+	"strconv"
+	"strings"
 
-	"github.com/disintegration/imaging"
 	"github.com/skip2/go-qrcode"
 )
 
 type RenderOptions struct {
-	Size            int    // px, e.g. 512
+	Size            int    // px width, e.g. 512 (default DefaultSize)
 	Color           string // Hex code e.g. "#FF0000"
 	BackgroundColor string // Hex code e.g. "#FFFFFF"
 	LogoPath        string // local file or fetched and cached
+	Format          string // png (default), svg or pdf
+	ErrorCorrection string // L, M (default), Q or H; forced to H when a logo is present
+	Margin          *int   // quiet zone in modules, nil means the symbology default (DefaultMargin for QR)
+
+	ModuleShape   string // square (default), dots, rounded or fluid
+	EyeFrameShape string // square (default), rounded or circle
+	EyeBallShape  string // square (default), rounded or circle
+	EyeFrameColor string // Hex code, defaults to Color
+	EyeBallColor  string // Hex code, defaults to Color
+
+	Gradient *Gradient // replaces Color on modules (and eyes without their own color)
+	Frame    *Frame    // optional border or banner with a call-to-action label
+	Caption  *Caption  // optional line of text above or below the code, inside any frame
+
+	Logo image.Image // already decoded logo (e.g. from the asset store), wins over LogoPath
+
+	DPI     int     // raster: stored as PNG pHYs / JPEG density; with WidthMM sets the pixel size
+	WidthMM float64 // physical width; vector output is laid out in mm
+
+	Symbology string // qr (default), datamatrix, aztec, pdf417, ean13, upca or code128
 }
 
+// DefaultMargin is the 4 module quiet zone required by ISO/IEC 18004
+const DefaultMargin = 4
+
+// MaxMargin keeps the quiet zone from swallowing the code itself
+const MaxMargin = 20
+
+var (
+	ErrInvalidErrorCorrection = errors.New("error correction must be one of L, M, Q, H")
+	ErrInvalidMargin          = fmt.Errorf("margin must be between 0 and %d modules", MaxMargin)
+)
+
 // RenderQRWithLogo generates a QR image bytes with optional logo
 func RenderQRWithLogo(content string, opts RenderOptions) ([]byte, error) {
-	if opts.Size == 0 {
-		opts.Size = 512
+	format, err := NormalizeFormat(opts.Format)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate QR base
-	qrImg, err := qrcode.New(content, qrcode.Medium)
+	size, err := SizeOptions{Pixels: opts.Size, DPI: opts.DPI, WidthMM: opts.WidthMM}.Resolve(format)
 	if err != nil {
 		return nil, err
 	}
-	qrImg.DisableBorder = true
 
-	// Apply Custom Colors if provided
-	if opts.Color != "" {
-		qrImg.ForegroundColor = parseHexColor(opts.Color)
+	d, err := buildCode(content, opts)
+	if err != nil {
+		return nil, err
 	}
-	if opts.BackgroundColor != "" {
-		qrImg.BackgroundColor = parseHexColor(opts.BackgroundColor)
+
+	// Size is the output width; captions and frames make the image taller
+	width, height := outputSize(d, size.Pixels)
+	aspect := d.Height / d.Width
+
+	switch format {
+	case FormatPDF:
+		if size.WidthMM > 0 {
+			pt := size.WidthMM / 25.4 * 72
+			return encodePDF(d, pt, pt*aspect)
+		}
+		return encodePDF(d, float64(width), float64(height))
+	case FormatSVG:
+		if size.WidthMM > 0 {
+			return encodeSVG(d, svgNum(size.WidthMM)+"mm", svgNum(size.WidthMM*aspect)+"mm")
+		}
+		return encodeSVG(d, strconv.Itoa(width), strconv.Itoa(height))
 	}
 
 	// Create the Image
-	qrPNG := qrImg.Image(opts.Size)
-
-	// Convert to NRGBA for imaging library manipulation
-	base := imaging.Clone(qrPNG)
-
-	// Overlay logo if provided
-	if opts.LogoPath != "" {
-		logoFile, err := os.Open(opts.LogoPath)
-		if err == nil {
-			defer logoFile.Close()
-			logo, _, err := image.Decode(logoFile)
-			if err == nil {
-				// Resize logo to 20% of QR size
-				logoSize := opts.Size / 5
-				logo = imaging.Resize(logo, logoSize, logoSize, imaging.Lanczos)
-
-				// Center position
-				x := (base.Bounds().Dx() - logo.Bounds().Dx()) / 2
-				y := (base.Bounds().Dy() - logo.Bounds().Dy()) / 2
-
-				base = imaging.Overlay(base, logo, image.Pt(x, y), 1.0)
-			}
-		}
+	base := rasterize(d, width, height)
+	return encodeRaster(base, format, size.DPI)
+}
+
+// buildCode encodes content and lays out the styled symbol with its
+// caption and frame, ready for any backend.
+func buildCode(content string, opts RenderOptions) (*drawing, error) {
+	// Encode QR (or another symbology) into modules
+	sym, err := encodeSymbol(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	margin, err := quietZone(opts.Margin, sym.QuietZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var logo image.Image
+	if sym.Finders {
+		logo = opts.logo()
 	}
 
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, base); err != nil {
+	// Colors and module/eye shapes
+	st, err := resolveStyle(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Refuse unreadable contrast; the borderline warnings are reported
+	// when the design is saved
+	if _, err := CheckContrast(opts); err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+
+	d := buildDrawing(sym, margin, st, logo)
+	if opts.Caption != nil {
+		if d, err = applyCaption(d, opts.Caption, st); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Frame != nil {
+		if d, err = applyFrame(d, opts.Frame, st); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// recoveryLevel maps L/M/Q/H to go-qrcode levels. A centered logo hides
+// modules, so codes with a logo always get the highest level.
+func recoveryLevel(level string, hasLogo bool) (qrcode.RecoveryLevel, error) {
+	var rl qrcode.RecoveryLevel
+	switch strings.ToUpper(strings.TrimSpace(level)) {
+	case "", "M":
+		rl = qrcode.Medium
+	case "L":
+		rl = qrcode.Low
+	case "Q":
+		rl = qrcode.High
+	case "H":
+		rl = qrcode.Highest
+	default:
+		return 0, ErrInvalidErrorCorrection
+	}
+
+	if hasLogo {
+		return qrcode.Highest, nil
+	}
+	return rl, nil
+}
+
+// quietZone validates the margin option; nil means the symbology's
+// own quiet zone (DefaultMargin for QR).
+func quietZone(margin *int, fallback int) (int, error) {
+	if margin == nil {
+		return fallback, nil
+	}
+	if *margin < 0 || *margin > MaxMargin {
+		return 0, ErrInvalidMargin
+	}
+	return *margin, nil
+}
+
+// logo returns the decoded logo, if any
+func (opts RenderOptions) logo() image.Image {
+	if opts.Logo != nil {
+		return opts.Logo
+	}
+	return loadLogo(opts.LogoPath)
+}
+
+// loadLogo decodes the logo file; a missing or broken logo is skipped
+// rather than failing the whole render.
+func loadLogo(path string) image.Image {
+	if path == "" {
+		return nil
+	}
+	logoFile, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer logoFile.Close()
+
+	logo, _, err := image.Decode(logoFile)
+	if err != nil {
+		return nil
+	}
+	return logo
 }
 
 // Helper: Parse Hex string to color.RGBA
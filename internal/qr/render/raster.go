@@ -0,0 +1,58 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/vector"
+)
+
+// rasterize renders the drawing into a w x h anti-aliased image
+func rasterize(d *drawing, w, h int) *image.NRGBA {
+	sx := float32(float64(w) / d.Width)
+	sy := float32(float64(h) / d.Height)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(d.Background), image.Point{}, draw.Src)
+
+	z := vector.NewRasterizer(w, h)
+	for _, it := range d.Items {
+		if it.Image != nil {
+			bx := int(float64(sx) * it.Box.X)
+			by := int(float64(sy) * it.Box.Y)
+			bw := int(float64(sx) * it.Box.W)
+			bh := int(float64(sy) * it.Box.H)
+			if bw <= 0 || bh <= 0 {
+				continue
+			}
+			img := imaging.Resize(it.Image, bw, bh, imaging.Lanczos)
+			dst = imaging.Overlay(dst, img, image.Pt(bx, by), 1.0)
+			continue
+		}
+
+		z.Reset(w, h)
+		for _, op := range it.Path {
+			switch op.Kind {
+			case opMove:
+				z.MoveTo(sx*float32(op.P.X), sy*float32(op.P.Y))
+			case opLine:
+				z.LineTo(sx*float32(op.P.X), sy*float32(op.P.Y))
+			case opCube:
+				z.CubeTo(
+					sx*float32(op.C1.X), sy*float32(op.C1.Y),
+					sx*float32(op.C2.X), sy*float32(op.C2.Y),
+					sx*float32(op.P.X), sy*float32(op.P.Y))
+			case opClose:
+				z.ClosePath()
+			}
+		}
+		var src image.Image = image.NewUniform(it.Fill.Color)
+		if it.Fill.Gradient != nil {
+			src = &gradientImage{g: it.Fill.Gradient, sx: float64(sx), sy: float64(sy), bounds: dst.Bounds()}
+		}
+		z.Draw(dst, dst.Bounds(), src, image.Point{})
+	}
+
+	return dst
+}
@@ -0,0 +1,223 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/makiuchi-d/gozxing"
+	zxaztec "github.com/makiuchi-d/gozxing/aztec"
+	zxdatamatrix "github.com/makiuchi-d/gozxing/datamatrix"
+	zxoned "github.com/makiuchi-d/gozxing/oned"
+	zxqr "github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/skip2/go-qrcode"
+)
+
+// MinScanScore is the score a design needs when scannability is enforced
+const MinScanScore = 70
+
+// ScanReport is the result of decoding a rendered code the way a phone
+// camera would, plus a few harsher variants of the same image.
+type ScanReport struct {
+	Scannable bool        `json:"scannable"` // the untouched image decodes to the expected content
+	Score     int         `json:"score"`     // 0..100
+	Decoded   string      `json:"decoded,omitempty"`
+	Checks    []ScanCheck `json:"checks"`
+	Warnings  []string    `json:"warnings"`
+}
+
+type ScanCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+}
+
+// scanTrials are the image variants a design is decoded under. The
+// weights add up to 100; everything but "original" simulates a worse
+// camera or print.
+var scanTrials = []struct {
+	name   string
+	weight int
+	warn   string
+	apply  func(img image.Image) image.Image
+}{
+	{"original", 55, "", func(img image.Image) image.Image { return img }},
+	{"small", 15, "fails when printed small or scanned from far away", func(img image.Image) image.Image {
+		return imaging.Resize(img, max(img.Bounds().Dx()/4, 1), 0, imaging.Box)
+	}},
+	{"blur", 15, "fails when the camera is slightly out of focus", func(img image.Image) image.Image {
+		return imaging.Blur(img, float64(img.Bounds().Dx())/200)
+	}},
+	{"glare", 15, "fails under glare or washed out printing", func(img image.Image) image.Image {
+		return imaging.AdjustBrightness(imaging.AdjustContrast(img, -50), 20)
+	}},
+}
+
+// scanReaders are the decoders per symbology. There is no pure Go PDF417
+// reader, so those codes can't be verified.
+var scanReaders = map[string]func() gozxing.Reader{
+	SymbologyQR:         func() gozxing.Reader { return zxqr.NewQRCodeReader() },
+	SymbologyDataMatrix: func() gozxing.Reader { return zxdatamatrix.NewDataMatrixReader() },
+	SymbologyAztec:      func() gozxing.Reader { return zxaztec.NewAztecReader() },
+	SymbologyEAN13:      zxoned.NewEAN13Reader,
+	SymbologyUPCA:       zxoned.NewUPCAReader,
+	SymbologyCode128:    zxoned.NewCode128Reader,
+}
+
+var ErrScanUnsupported = errors.New("scannability check is not available for this symbology")
+
+// designPenalty is taken off the score for each design level warning
+// (contrast, inverted colors, logo size) on top of the decode trials.
+const designPenalty = 10
+
+// VerifyScannability decodes a rendered PNG (composited or not) and
+// checks it against content. opts is the design it was rendered with,
+// used to explain failures.
+func VerifyScannability(pngBytes []byte, content string, opts RenderOptions) (*ScanReport, error) {
+	symbology, err := NormalizeSymbology(opts.Symbology)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := scanReaders[symbology]
+	if !ok {
+		return nil, ErrScanUnsupported
+	}
+
+	// Compare against what a scanner returns, e.g. EAN with check digit
+	sym, err := encodeSymbol(content, opts)
+	if err != nil {
+		return nil, err
+	}
+	content = sym.Content
+
+	img, _, err := image.Decode(bytes.NewReader(pngBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScanReport{Checks: []ScanCheck{}, Warnings: []string{}}
+
+	for _, trial := range scanTrials {
+		text, ok := decode(reader(), trial.apply(img), false)
+		passed := ok && text == content
+		if trial.name == "original" {
+			report.Decoded = text
+			report.Scannable = passed
+			if ok && !passed {
+				report.Warnings = append(report.Warnings, "decodes to different content than expected")
+			}
+		}
+		if passed {
+			report.Score += trial.weight
+		} else if trial.warn != "" && report.Scannable {
+			report.Warnings = append(report.Warnings, trial.warn)
+		}
+		report.Checks = append(report.Checks, ScanCheck{Name: trial.name, Passed: passed})
+	}
+
+	design := designWarnings(content, opts)
+	if !report.Scannable && !invertedColors(opts) {
+		// e.g. a dark scene or a frame color swapped in behind the modules
+		if _, ok := decode(reader(), img, true); ok {
+			design = append(design, "only decodes with inverted colors, many camera apps can't read light on dark codes")
+		}
+	}
+	report.Warnings = append(append([]string{}, design...), report.Warnings...)
+	report.Score = max(report.Score-designPenalty*len(design), 0)
+	if !report.Scannable {
+		report.Score = 0
+	}
+	return report, nil
+}
+
+// decode runs the pure Go ZXing port; inverted reads light-on-dark
+func decode(reader gozxing.Reader, img image.Image, inverted bool) (string, bool) {
+	src := gozxing.NewLuminanceSourceFromImage(img)
+	if inverted {
+		src = gozxing.NewInvertedLuminanceSource(src)
+	}
+	bmp, err := gozxing.NewBinaryBitmap(gozxing.NewHybridBinarizer(src))
+	if err != nil {
+		return "", false
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_TRY_HARDER: true}
+	res, err := reader.Decode(bmp, hints)
+	if err != nil {
+		return "", false
+	}
+	return res.GetText(), true
+}
+
+// designWarnings flags design choices that hurt scanning even when the
+// rendered image happens to decode.
+func designWarnings(content string, opts RenderOptions) []string {
+	var warnings []string
+
+	contrast, err := CheckContrast(opts)
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	warnings = append(warnings, contrast...)
+
+	if invertedColors(opts) {
+		warnings = append(warnings, "colors are inverted (light modules on a dark background), many camera apps can't read them")
+	}
+
+	if sym, _ := NormalizeSymbology(opts.Symbology); sym == SymbologyQR && opts.logo() != nil {
+		if w := logoCoverageWarning(content, opts); w != "" {
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+// invertedColors reports light modules on a darker background
+func invertedColors(opts RenderOptions) bool {
+	fg, bg := color.RGBA{A: 0xff}, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if opts.Color != "" {
+		fg = parseHexColor(opts.Color)
+	}
+	if opts.BackgroundColor != "" {
+		bg = parseHexColor(opts.BackgroundColor)
+	}
+	return relativeLuminance(fg) > relativeLuminance(bg)
+}
+
+// recoveryCapacity is roughly how much of the symbol each error
+// correction level can rebuild
+var recoveryCapacity = map[qrcode.RecoveryLevel]float64{
+	qrcode.Low:     0.07,
+	qrcode.Medium:  0.15,
+	qrcode.High:    0.25,
+	qrcode.Highest: 0.30,
+}
+
+// logoCoverageWarning compares the modules hidden behind the logo with
+// what the error correction level can recover. Past half the capacity
+// there's little margin left for dirt, glare or a bent label.
+func logoCoverageWarning(content string, opts RenderOptions) string {
+	level, err := recoveryLevel(opts.ErrorCorrection, true)
+	if err != nil {
+		return ""
+	}
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return ""
+	}
+	q.DisableBorder = true
+	n := len(q.Bitmap())
+
+	hidden := math.Pow(math.Ceil(float64(n)*logoScale), 2)
+	// Finder patterns, separators and format info can't hold data
+	usable := float64(n*n - 3*64 - 2*15)
+	coverage := hidden / usable
+
+	capacity := recoveryCapacity[level]
+	if coverage > capacity/2 {
+		return fmt.Sprintf("logo hides %.0f%% of the modules, error correction can only recover about %.0f%%", coverage*100, capacity*100)
+	}
+	return ""
+}
@@ -0,0 +1,293 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Module shapes
+const (
+	ShapeSquare  = "square"
+	ShapeDots    = "dots"
+	ShapeRounded = "rounded"
+	ShapeFluid   = "fluid" // connected modules, only outer corners rounded
+)
+
+// Finder pattern ("eye") shapes, used for both the frame and the ball
+const (
+	EyeSquare  = "square"
+	EyeRounded = "rounded"
+	EyeCircle  = "circle"
+)
+
+var ErrInvalidShape = errors.New("invalid module or eye shape")
+
+// finderSize is the 7x7 finder pattern in the three corners of a QR code
+const finderSize = 7
+
+// logoScale is the side of the centered logo relative to the code,
+// quiet zone excluded
+const logoScale = 0.2
+
+// codeStyle is the resolved look of the modules and eyes. Nil eye
+// colors follow the module fill, gradient included.
+type codeStyle struct {
+	Foreground    color.RGBA
+	Background    color.RGBA
+	Gradient      *Gradient
+	GradientStops []colorStop
+	ModuleShape   string
+	EyeFrameShape string
+	EyeBallShape  string
+	EyeFrameColor *color.RGBA
+	EyeBallColor  *color.RGBA
+}
+
+// resolveStyle validates shape names and gradients and fills in
+// defaults: square everything, eyes in the foreground fill.
+func resolveStyle(opts RenderOptions) (codeStyle, error) {
+	st := codeStyle{
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+	if opts.Color != "" {
+		st.Foreground = parseHexColor(opts.Color)
+	}
+	if opts.BackgroundColor != "" {
+		st.Background = parseHexColor(opts.BackgroundColor)
+	}
+
+	var err error
+	if st.ModuleShape, err = pickShape(opts.ModuleShape, ShapeSquare, ShapeDots, ShapeRounded, ShapeFluid); err != nil {
+		return st, err
+	}
+	if st.EyeFrameShape, err = pickShape(opts.EyeFrameShape, EyeSquare, EyeRounded, EyeCircle); err != nil {
+		return st, err
+	}
+	if st.EyeBallShape, err = pickShape(opts.EyeBallShape, EyeSquare, EyeRounded, EyeCircle); err != nil {
+		return st, err
+	}
+
+	if opts.Gradient != nil {
+		if st.GradientStops, err = opts.Gradient.colorStops(); err != nil {
+			return st, err
+		}
+		st.Gradient = opts.Gradient
+	}
+
+	if opts.EyeFrameColor != "" {
+		c := parseHexColor(opts.EyeFrameColor)
+		st.EyeFrameColor = &c
+	}
+	if opts.EyeBallColor != "" {
+		c := parseHexColor(opts.EyeBallColor)
+		st.EyeBallColor = &c
+	}
+
+	return st, nil
+}
+
+// modulePaint is the fill for dark modules, with any gradient spread
+// over the code area.
+func (st codeStyle) modulePaint(code rect) paint {
+	if st.Gradient == nil {
+		return solid(st.Foreground)
+	}
+	return paint{Color: st.Foreground, Gradient: st.Gradient.resolve(st.GradientStops, code)}
+}
+
+// pickShape returns the normalized value, or the first allowed value
+// (the default) when s is empty.
+func pickShape(s string, allowed ...string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return allowed[0], nil
+	}
+	for _, a := range allowed {
+		if s == a {
+			return s, nil
+		}
+	}
+	return "", ErrInvalidShape
+}
+
+// Human readable line under linear symbols, in modules
+const (
+	linearTextSize = 9
+	linearTextGap  = 1.5
+	linearTextFont = "go-mono"
+)
+
+// buildDrawing lays out the modules of sym (offset by margin modules on
+// every side), the three finder eyes of a QR code, the optional centered
+// logo and the digits under a linear barcode.
+func buildDrawing(sym *Symbol, margin int, st codeStyle, logo image.Image) *drawing {
+	bitmap := sym.Modules
+	rows, cols := len(bitmap), 0
+	if rows > 0 {
+		cols = len(bitmap[0])
+	}
+	// Linear codes are a single row of modules stretched into bars
+	rowH := 1.0
+	if sym.Linear() {
+		rowH = sym.BarHeight / float64(rows)
+	}
+	m := float64(margin)
+	codeW, codeH := float64(cols), float64(rows)*rowH
+
+	var text textLine
+	textH := 0.0
+	if sym.Linear() && sym.Text != "" {
+		if font, err := loadFont(linearTextFont); err == nil {
+			text = textLine{font: font, text: sym.Text, size: linearTextSize}
+			if tw := text.width(); tw > codeW {
+				text.size *= codeW / tw
+			}
+			textH = linearTextGap + text.capHeight()
+		}
+	}
+
+	d := &drawing{Width: codeW + 2*m, Height: codeH + textH + 2*m, Background: st.Background}
+
+	dark := func(x, y int) bool {
+		return y >= 0 && y < rows && x >= 0 && x < cols && bitmap[y][x] && !(sym.Finders && inFinder(x, y, cols))
+	}
+
+	shape := st.ModuleShape
+	if sym.Bars {
+		shape = ShapeSquare
+	}
+
+	var modules path
+	if shape == ShapeSquare {
+		// Merge horizontal runs so vector output stays small
+		for y := 0; y < rows; y++ {
+			for x := 0; x < cols; {
+				if !dark(x, y) {
+					x++
+					continue
+				}
+				start := x
+				for x < cols && dark(x, y) {
+					x++
+				}
+				modules.rect(float64(start)+m, float64(y)*rowH+m, float64(x-start), rowH)
+			}
+		}
+	} else {
+		for y := 0; y < rows; y++ {
+			for x := 0; x < cols; x++ {
+				if dark(x, y) {
+					modulePath(&modules, shape, float64(x)+m, float64(y)+m, x, y, dark)
+				}
+			}
+		}
+	}
+	fg := st.modulePaint(rect{X: m, Y: m, W: codeW, H: codeH})
+	d.fill(modules, fg)
+
+	if sym.Finders {
+		n := cols
+		var frames, balls path
+		for _, corner := range [][2]int{{0, 0}, {n - finderSize, 0}, {0, n - finderSize}} {
+			x := float64(corner[0]) + m
+			y := float64(corner[1]) + m
+			frames.append(eyeFramePath(st.EyeFrameShape, x, y))
+			balls.append(eyeBallPath(st.EyeBallShape, x+2, y+2))
+		}
+		d.fill(frames, eyePaint(st.EyeFrameColor, fg))
+		d.fill(balls, eyePaint(st.EyeBallColor, fg))
+	}
+
+	if logo != nil {
+		logoSize := codeW * logoScale
+		offset := m + (codeW-logoSize)/2
+		d.image(logo, rect{X: offset, Y: offset, W: logoSize, H: logoSize})
+	}
+
+	if textH > 0 {
+		x := m + (codeW-text.width())/2
+		d.fill(text.outline(x, m+codeH+textH), fg)
+	}
+
+	return d
+}
+
+func eyePaint(c *color.RGBA, fallback paint) paint {
+	if c == nil {
+		return fallback
+	}
+	return solid(*c)
+}
+
+func inFinder(x, y, n int) bool {
+	inLow := func(v int) bool { return v < finderSize }
+	inHigh := func(v int) bool { return v >= n-finderSize }
+	return (inLow(x) && inLow(y)) || (inHigh(x) && inLow(y)) || (inLow(x) && inHigh(y))
+}
+
+// modulePath adds one dark module at (px, py); dark reports neighbours
+// so the fluid style can round only the corners that are exposed.
+func modulePath(p *path, shape string, px, py float64, x, y int, dark func(x, y int) bool) {
+	switch shape {
+	case ShapeDots:
+		p.circle(px+0.5, py+0.5, 0.45)
+	case ShapeRounded:
+		const r = 0.3
+		p.roundedRect(px+0.05, py+0.05, 0.9, 0.9, [4]float64{r, r, r, r})
+	case ShapeFluid:
+		const r = 0.5
+		up, down := dark(x, y-1), dark(x, y+1)
+		left, right := dark(x-1, y), dark(x+1, y)
+		var radii [4]float64
+		if !up && !left {
+			radii[0] = r
+		}
+		if !up && !right {
+			radii[1] = r
+		}
+		if !down && !right {
+			radii[2] = r
+		}
+		if !down && !left {
+			radii[3] = r
+		}
+		p.roundedRect(px, py, 1, 1, radii)
+	default:
+		p.rect(px, py, 1, 1)
+	}
+}
+
+// eyeFramePath is the 7x7 outer ring with a 5x5 hole
+func eyeFramePath(shape string, x, y float64) path {
+	var outer, inner path
+	switch shape {
+	case EyeRounded:
+		outer.roundedRect(x, y, 7, 7, [4]float64{2, 2, 2, 2})
+		inner.roundedRect(x+1, y+1, 5, 5, [4]float64{1.2, 1.2, 1.2, 1.2})
+	case EyeCircle:
+		outer.circle(x+3.5, y+3.5, 3.5)
+		inner.circle(x+3.5, y+3.5, 2.5)
+	default:
+		outer.rect(x, y, 7, 7)
+		inner.rect(x+1, y+1, 5, 5)
+	}
+	outer.append(inner.reversed())
+	return outer
+}
+
+// eyeBallPath is the 3x3 center of the finder pattern
+func eyeBallPath(shape string, x, y float64) path {
+	var p path
+	switch shape {
+	case EyeRounded:
+		p.roundedRect(x, y, 3, 3, [4]float64{0.9, 0.9, 0.9, 0.9})
+	case EyeCircle:
+		p.circle(x+1.5, y+1.5, 1.5)
+	default:
+		p.rect(x, y, 3, 3)
+	}
+	return p
+}
@@ -0,0 +1,184 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SheetLayout is a grid of labels on a page, all lengths in mm. Labels
+// fill the grid row by row and continue on the next page.
+type SheetLayout struct {
+	Name        string  `json:"name,omitempty"`
+	PageWidth   float64 `json:"page_width_mm"`
+	PageHeight  float64 `json:"page_height_mm"`
+	Columns     int     `json:"columns"`
+	Rows        int     `json:"rows"`
+	LabelWidth  float64 `json:"label_width_mm"`
+	LabelHeight float64 `json:"label_height_mm"`
+	MarginTop   float64 `json:"margin_top_mm"`
+	MarginLeft  float64 `json:"margin_left_mm"`
+	GutterX     float64 `json:"gutter_x_mm"` // between columns
+	GutterY     float64 `json:"gutter_y_mm"` // between rows
+	Padding     float64 `json:"padding_mm"`  // inside each label, keeps the code off the cut line
+}
+
+// Page sizes in mm
+const (
+	letterWidth  = 215.9
+	letterHeight = 279.4
+	a4Width      = 210
+	a4Height     = 297
+)
+
+// sheetPresets are common label stocks
+var sheetPresets = map[string]SheetLayout{
+	// 2" square, 12 per Letter sheet
+	"avery-22806": {
+		PageWidth: letterWidth, PageHeight: letterHeight, Columns: 3, Rows: 4,
+		LabelWidth: 50.8, LabelHeight: 50.8, MarginTop: 15.875, MarginLeft: 15.875,
+		GutterX: 15.875, GutterY: 14.817, Padding: 3,
+	},
+	// 1" x 2-5/8" address labels, 30 per Letter sheet
+	"avery-5160": {
+		PageWidth: letterWidth, PageHeight: letterHeight, Columns: 3, Rows: 10,
+		LabelWidth: 66.675, LabelHeight: 25.4, MarginTop: 12.7, MarginLeft: 4.7625,
+		GutterX: 3.175, Padding: 1.5,
+	},
+	// 70 x 37 mm, 24 per A4 sheet, edge to edge
+	"a4-3x8": {
+		PageWidth: a4Width, PageHeight: a4Height, Columns: 3, Rows: 8,
+		LabelWidth: 70, LabelHeight: 37, MarginTop: 0.5, Padding: 2,
+	},
+	// 1.5" square, 24 per Letter sheet, evenly spaced
+	"letter-4x6": {
+		PageWidth: letterWidth, PageHeight: letterHeight, Columns: 4, Rows: 6,
+		LabelWidth: 38.1, LabelHeight: 38.1, MarginTop: 7.257, MarginLeft: 12.7,
+		GutterX: 12.7, GutterY: 7.257, Padding: 2,
+	},
+}
+
+// MaxSheetLabels caps a single PDF
+const MaxSheetLabels = 2000
+
+var (
+	ErrUnknownSheet  = errors.New("unknown label sheet")
+	ErrInvalidSheet  = errors.New("label sheet needs positive sizes and a grid that fits on the page")
+	ErrTooManyLabels = fmt.Errorf("a label sheet PDF can hold at most %d labels", MaxSheetLabels)
+)
+
+// SheetPresets lists the built-in label stocks by name
+func SheetPresets() []SheetLayout {
+	names := make([]string, 0, len(sheetPresets))
+	for name := range sheetPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]SheetLayout, 0, len(names))
+	for _, name := range names {
+		l := sheetPresets[name]
+		l.Name = name
+		out = append(out, l)
+	}
+	return out
+}
+
+// LookupSheet returns a preset by name
+func LookupSheet(name string) (SheetLayout, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	l, ok := sheetPresets[name]
+	if !ok {
+		return SheetLayout{}, ErrUnknownSheet
+	}
+	l.Name = name
+	return l, nil
+}
+
+// Validate checks the grid fits on the page (with a little slack for
+// rounded template numbers)
+func (l SheetLayout) Validate() error {
+	const slack = 0.5
+	if l.PageWidth <= 0 || l.PageHeight <= 0 || l.LabelWidth <= 0 || l.LabelHeight <= 0 ||
+		l.Columns < 1 || l.Rows < 1 ||
+		l.MarginTop < 0 || l.MarginLeft < 0 || l.GutterX < 0 || l.GutterY < 0 || l.Padding < 0 {
+		return ErrInvalidSheet
+	}
+	if 2*l.Padding >= math.Min(l.LabelWidth, l.LabelHeight) {
+		return ErrInvalidSheet
+	}
+	w := l.MarginLeft + float64(l.Columns)*l.LabelWidth + float64(l.Columns-1)*l.GutterX
+	h := l.MarginTop + float64(l.Rows)*l.LabelHeight + float64(l.Rows-1)*l.GutterY
+	if w > l.PageWidth+slack || h > l.PageHeight+slack {
+		return ErrInvalidSheet
+	}
+	return nil
+}
+
+// Label is one code on a sheet. Format and size options are ignored,
+// the code is fitted to the label.
+type Label struct {
+	Content string
+	Options RenderOptions
+}
+
+// ptPerMM converts mm to PDF points
+const ptPerMM = 72 / 25.4
+
+// RenderSheet lays the labels out on as many pages as needed and returns
+// the PDF. outlines draws a hairline around every label to check the
+// alignment on plain paper before printing on label stock.
+func RenderSheet(labels []Label, layout SheetLayout, outlines bool) ([]byte, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	if len(labels) > MaxSheetLabels {
+		return nil, ErrTooManyLabels
+	}
+
+	doc := newPDF()
+	perPage := layout.Columns * layout.Rows
+	var pg *pdfPage
+	outline := color.RGBA{R: 0xbb, G: 0xbb, B: 0xbb, A: 0xff}
+
+	for i, label := range labels {
+		if i%perPage == 0 {
+			pg = doc.addPage(layout.PageWidth*ptPerMM, layout.PageHeight*ptPerMM)
+		}
+
+		d, err := buildCode(label.Content, label.Options)
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %w", i+1, err)
+		}
+
+		cell := i % perPage
+		col, row := cell%layout.Columns, cell/layout.Columns
+		x := layout.MarginLeft + float64(col)*(layout.LabelWidth+layout.GutterX)
+		y := layout.MarginTop + float64(row)*(layout.LabelHeight+layout.GutterY)
+
+		if outlines {
+			pg.strokeRect(x*ptPerMM, y*ptPerMM, layout.LabelWidth*ptPerMM, layout.LabelHeight*ptPerMM, 0.25, outline)
+		}
+
+		// Fit the code into the padded label, centered, keeping its aspect
+		boxW := layout.LabelWidth - 2*layout.Padding
+		boxH := layout.LabelHeight - 2*layout.Padding
+		scale := math.Min(boxW/d.Width, boxH/d.Height)
+		w, h := d.Width*scale, d.Height*scale
+		x += layout.Padding + (boxW-w)/2
+		y += layout.Padding + (boxH-h)/2
+
+		if err := pg.drawDrawing(d, x*ptPerMM, y*ptPerMM, w*ptPerMM, h*ptPerMM); err != nil {
+			return nil, err
+		}
+	}
+
+	if pg == nil {
+		// Still a valid (blank) page rather than a broken PDF
+		doc.addPage(layout.PageWidth*ptPerMM, layout.PageHeight*ptPerMM)
+	}
+	return doc.bytes()
+}
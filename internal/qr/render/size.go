@@ -0,0 +1,71 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Output size bounds. Raster images above MaxSize get expensive to
+// render and hold in memory; billboards should use svg or pdf, which
+// scale freely.
+const (
+	DefaultSize   = 512
+	MinSize       = 64
+	MaxSize       = 4096 // px
+	DefaultDPI    = 300  // used when only a physical width is given
+	MinDPI        = 72
+	MaxDPI        = 1200
+	MaxPhysicalMM = 5000 // 5 m
+)
+
+var (
+	ErrInvalidSize   = fmt.Errorf("size must be between %d and %d px", MinSize, MaxSize)
+	ErrInvalidDPI    = fmt.Errorf("dpi must be between %d and %d", MinDPI, MaxDPI)
+	ErrInvalidMM     = fmt.Errorf("physical width must be above 0 and at most %d mm", MaxPhysicalMM)
+	ErrTooManyPixels = errors.New("physical width at this dpi is too many pixels, lower the dpi or use svg/pdf")
+)
+
+// SizeOptions is the requested output size: pixels, or a physical
+// width printed at DPI. Zero values mean "not set".
+type SizeOptions struct {
+	Pixels  int
+	DPI     int
+	WidthMM float64
+}
+
+// Resolve validates the request and returns the pixel width (also used
+// as the point size of vector output without a physical width).
+func (s SizeOptions) Resolve(format string) (SizeOptions, error) {
+	if s.DPI != 0 && (s.DPI < MinDPI || s.DPI > MaxDPI) {
+		return s, ErrInvalidDPI
+	}
+
+	if s.WidthMM != 0 {
+		if s.WidthMM < 0 || s.WidthMM > MaxPhysicalMM {
+			return s, ErrInvalidMM
+		}
+		if s.DPI == 0 {
+			s.DPI = DefaultDPI
+		}
+		px := int(math.Round(s.WidthMM / 25.4 * float64(s.DPI)))
+		// Vector output is laid out in mm, the pixel count only matters
+		// for raster formats
+		if IsVector(format) {
+			px = max(min(px, MaxSize), MinSize)
+		}
+		if px > MaxSize {
+			return s, fmt.Errorf("%w (%d px)", ErrTooManyPixels, px)
+		}
+		s.Pixels = max(px, MinSize)
+		return s, nil
+	}
+
+	if s.Pixels == 0 {
+		s.Pixels = DefaultSize
+	}
+	if s.Pixels < MinSize || s.Pixels > MaxSize {
+		return s, ErrInvalidSize
+	}
+	return s, nil
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// encodeSVG writes the drawing as a standalone SVG document of width x
+// height (SVG lengths such as "600" or "50mm"), with the drawing's
+// module grid as the viewBox.
+func encodeSVG(d *drawing, width, height string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf,
+		`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		width, height, svgNum(d.Width), svgNum(d.Height))
+
+	fmt.Fprintf(&buf, `<rect width="%s" height="%s" %s/>`+"\n",
+		svgNum(d.Width), svgNum(d.Height), svgFill(d.Background))
+
+	gradients := map[*gradientFill]string{}
+
+	for _, it := range d.Items {
+		if it.Image != nil {
+			var img bytes.Buffer
+			if err := png.Encode(&img, it.Image); err != nil {
+				return nil, err
+			}
+			href := "data:image/png;base64," + base64.StdEncoding.EncodeToString(img.Bytes())
+			fmt.Fprintf(&buf,
+				`<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none" xlink:href="%s"/>`+"\n",
+				svgNum(it.Box.X), svgNum(it.Box.Y), svgNum(it.Box.W), svgNum(it.Box.H), href)
+			continue
+		}
+
+		fill := svgFill(it.Fill.Color)
+		if g := it.Fill.Gradient; g != nil {
+			id, ok := gradients[g]
+			if !ok {
+				id = "g" + strconv.Itoa(len(gradients)+1)
+				gradients[g] = id
+				writeSVGGradient(&buf, id, g)
+			}
+			fill = `fill="url(#` + id + `)"`
+		}
+
+		fmt.Fprintf(&buf, `<path %s d="%s"/>`+"\n", fill, svgPathData(it.Path))
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+func writeSVGGradient(buf *bytes.Buffer, id string, g *gradientFill) {
+	buf.WriteString("<defs>")
+	if g.Radial {
+		fmt.Fprintf(buf, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s">`,
+			id, svgNum(g.X0), svgNum(g.Y0), svgNum(g.R))
+	} else {
+		fmt.Fprintf(buf, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s">`,
+			id, svgNum(g.X0), svgNum(g.Y0), svgNum(g.X1), svgNum(g.Y1))
+	}
+	for _, s := range g.Stops {
+		fmt.Fprintf(buf, `<stop offset="%s" stop-color="#%02x%02x%02x"`, svgNum(s.Offset), s.Color.R, s.Color.G, s.Color.B)
+		if s.Color.A != 0xff {
+			fmt.Fprintf(buf, ` stop-opacity="%s"`, svgNum(float64(s.Color.A)/255))
+		}
+		buf.WriteString("/>")
+	}
+	if g.Radial {
+		buf.WriteString("</radialGradient>")
+	} else {
+		buf.WriteString("</linearGradient>")
+	}
+	buf.WriteString("</defs>\n")
+}
+
+func svgPathData(p path) string {
+	var b bytes.Buffer
+	for _, op := range p {
+		switch op.Kind {
+		case opMove:
+			b.WriteString("M" + svgNum(op.P.X) + " " + svgNum(op.P.Y))
+		case opLine:
+			b.WriteString("L" + svgNum(op.P.X) + " " + svgNum(op.P.Y))
+		case opCube:
+			b.WriteString("C" + svgNum(op.C1.X) + " " + svgNum(op.C1.Y) + " " +
+				svgNum(op.C2.X) + " " + svgNum(op.C2.Y) + " " +
+				svgNum(op.P.X) + " " + svgNum(op.P.Y))
+		case opClose:
+			b.WriteString("Z")
+		}
+	}
+	return b.String()
+}
+
+func svgFill(c color.RGBA) string {
+	s := fmt.Sprintf(`fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		s += fmt.Sprintf(` fill-opacity="%s"`, svgNum(float64(c.A)/255))
+	}
+	return s
+}
+
+// svgNum formats coordinates compactly (4 decimals, no trailing zeros)
+func svgNum(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,293 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/aztec"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/pdf417"
+	"github.com/skip2/go-qrcode"
+)
+
+// Symbologies supported by RenderQRWithLogo
+const (
+	SymbologyQR         = "qr"
+	SymbologyDataMatrix = "datamatrix"
+	SymbologyAztec      = "aztec"
+	SymbologyPDF417     = "pdf417"
+	SymbologyEAN13      = "ean13"
+	SymbologyUPCA       = "upca"
+	SymbologyCode128    = "code128"
+)
+
+var (
+	ErrUnsupportedSymbology = errors.New("unsupported symbology")
+	ErrInvalidContent       = errors.New("content can't be encoded in this symbology")
+)
+
+// Symbol is an encoded barcode before any styling: a grid of dark and
+// light modules plus what the renderer needs to lay it out.
+type Symbol struct {
+	Modules   [][]bool // [row][column], true is dark
+	QuietZone int      // modules of clear space the spec asks for
+	Content   string   // what a scanner reads back, check digit included
+
+	// Finders marks QR finder patterns, drawn as styled eyes; only QR has
+	// the spare error correction to hide modules under a logo.
+	Finders bool
+	// Bars keeps modules square so adjacent bars and stacked rows join up
+	// (linear codes and PDF417); dots or rounded modules would break them.
+	Bars bool
+	// BarHeight is the height of a linear (single row) symbol in modules,
+	// with Text printed underneath for humans.
+	BarHeight float64
+	Text      string
+}
+
+// Linear reports a one dimensional barcode
+func (s *Symbol) Linear() bool {
+	return s.BarHeight > 0
+}
+
+var (
+	// encoders turn content into a Symbol. opts is the full design so an
+	// encoder can map ErrorCorrection (or ignore it).
+	encoders = map[string]func(content string, opts RenderOptions) (*Symbol, error){
+		SymbologyQR:         encodeQR,
+		SymbologyDataMatrix: encodeDataMatrix,
+		SymbologyAztec:      encodeAztec,
+		SymbologyPDF417:     encodePDF417,
+		SymbologyEAN13:      encodeEAN13,
+		SymbologyUPCA:       encodeUPCA,
+		SymbologyCode128:    encodeCode128,
+	}
+	linearSymbologies = map[string]bool{
+		SymbologyEAN13:   true,
+		SymbologyUPCA:    true,
+		SymbologyCode128: true,
+	}
+)
+
+// NormalizeSymbology lower-cases the name and defaults empty values to QR.
+// It returns ErrUnsupportedSymbology for anything without an encoder.
+func NormalizeSymbology(name string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(name))
+	switch s {
+	case "":
+		return SymbologyQR, nil
+	case "data_matrix", "data-matrix":
+		s = SymbologyDataMatrix
+	case "ean", "ean-13", "ean_13":
+		s = SymbologyEAN13
+	case "upc", "upc-a", "upc_a":
+		s = SymbologyUPCA
+	case "code-128", "code_128":
+		s = SymbologyCode128
+	}
+
+	if _, ok := encoders[s]; !ok {
+		return "", ErrUnsupportedSymbology
+	}
+	return s, nil
+}
+
+// Symbologies lists the supported symbology names
+func Symbologies() []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsLinear reports a (normalized) one dimensional symbology. Those hold
+// product numbers or short IDs, not URLs.
+func IsLinear(symbology string) bool {
+	return linearSymbologies[symbology]
+}
+
+// encodeSymbol runs the encoder for opts.Symbology
+func encodeSymbol(content string, opts RenderOptions) (*Symbol, error) {
+	name, err := NormalizeSymbology(opts.Symbology)
+	if err != nil {
+		return nil, err
+	}
+	return encoders[name](content, opts)
+}
+
+// ValidateContent checks that content fits the symbology (digits for
+// EAN/UPC, ASCII for Code 128, capacity for the 2D codes) without
+// rendering anything.
+func ValidateContent(symbology, content string) error {
+	_, err := encodeSymbol(content, RenderOptions{Symbology: symbology})
+	return err
+}
+
+func encodeQR(content string, opts RenderOptions) (*Symbol, error) {
+	level, err := recoveryLevel(opts.ErrorCorrection, opts.logo() != nil)
+	if err != nil {
+		return nil, err
+	}
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = true
+	return &Symbol{Modules: q.Bitmap(), QuietZone: DefaultMargin, Content: content, Finders: true}, nil
+}
+
+func encodeDataMatrix(content string, opts RenderOptions) (*Symbol, error) {
+	// ECC 200 has a fixed error correction level per symbol size
+	if _, err := recoveryLevel(opts.ErrorCorrection, false); err != nil {
+		return nil, err
+	}
+	bc, err := datamatrix.Encode(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
+	return &Symbol{Modules: modulesOf(bc), QuietZone: 1, Content: content}, nil
+}
+
+// aztecECC is the minimum share of error correction codewords per level
+var aztecECC = map[qrcode.RecoveryLevel]int{
+	qrcode.Low:     10,
+	qrcode.Medium:  23,
+	qrcode.High:    36,
+	qrcode.Highest: 50,
+}
+
+func encodeAztec(content string, opts RenderOptions) (*Symbol, error) {
+	level, err := recoveryLevel(opts.ErrorCorrection, false)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := aztec.Encode([]byte(content), aztecECC[level], 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
+	// Aztec needs no quiet zone, one module keeps it off the image edge
+	return &Symbol{Modules: modulesOf(bc), QuietZone: 1, Content: content}, nil
+}
+
+// pdf417Security is the PDF417 security level (0-8) per level
+var pdf417Security = map[qrcode.RecoveryLevel]byte{
+	qrcode.Low:     2,
+	qrcode.Medium:  4,
+	qrcode.High:    5,
+	qrcode.Highest: 6,
+}
+
+func encodePDF417(content string, opts RenderOptions) (*Symbol, error) {
+	level, err := recoveryLevel(opts.ErrorCorrection, false)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := pdf417.Encode(content, pdf417Security[level])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
+	return &Symbol{Modules: modulesOf(bc), QuietZone: 2, Content: content, Bars: true}, nil
+}
+
+// Linear symbol proportions in modules: EAN/UPC bars are 22.85mm tall on
+// a 0.33mm module; Code 128 gets at least 15% of its length.
+const (
+	eanBarHeight     = 69
+	linearQuietZone  = 10
+	code128MinHeight = 24
+)
+
+// encodeEAN13 takes 12 digits (the check digit is added) or 13
+func encodeEAN13(content string, opts RenderOptions) (*Symbol, error) {
+	digits := strings.TrimSpace(content)
+	if !allDigits(digits) || (len(digits) != 12 && len(digits) != 13) {
+		return nil, fmt.Errorf("%w: EAN-13 needs 12 or 13 digits", ErrInvalidContent)
+	}
+	bc, err := ean.Encode(digits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
+	code := bc.Content()
+	return &Symbol{
+		Modules:   modulesOf(bc),
+		QuietZone: linearQuietZone,
+		Content:   code,
+		Bars:      true,
+		BarHeight: eanBarHeight,
+		Text:      code,
+	}, nil
+}
+
+// encodeUPCA takes 11 digits (the check digit is added) or 12. A UPC-A
+// symbol is an EAN-13 with a leading zero.
+func encodeUPCA(content string, opts RenderOptions) (*Symbol, error) {
+	digits := strings.TrimSpace(content)
+	if !allDigits(digits) || (len(digits) != 11 && len(digits) != 12) {
+		return nil, fmt.Errorf("%w: UPC-A needs 11 or 12 digits", ErrInvalidContent)
+	}
+	sym, err := encodeEAN13("0"+digits, opts)
+	if err != nil {
+		return nil, err
+	}
+	sym.Content = sym.Content[1:]
+	sym.Text = sym.Content
+	return sym, nil
+}
+
+func encodeCode128(content string, opts RenderOptions) (*Symbol, error) {
+	for _, r := range content {
+		if r > unicode.MaxASCII {
+			return nil, fmt.Errorf("%w: Code 128 only holds ASCII", ErrInvalidContent)
+		}
+	}
+	bc, err := code128.Encode(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
+	modules := modulesOf(bc)
+	return &Symbol{
+		Modules:   modules,
+		QuietZone: linearQuietZone,
+		Content:   content,
+		Bars:      true,
+		BarHeight: max(float64(len(modules[0]))*0.15, code128MinHeight),
+		Text:      content,
+	}, nil
+}
+
+// modulesOf samples a boombuler barcode, which renders one pixel per
+// module (PDF417 rows are a few pixels tall, kept as repeated rows).
+func modulesOf(bc barcode.Barcode) [][]bool {
+	b := bc.Bounds()
+	modules := make([][]bool, b.Dy())
+	for y := range modules {
+		modules[y] = make([]bool, b.Dx())
+		for x := range modules[y] {
+			modules[y][x] = isDark(bc, b.Min.X+x, b.Min.Y+y)
+		}
+	}
+	return modules
+}
+
+func isDark(img image.Image, x, y int) bool {
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < 0x80
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -2,6 +2,7 @@ package qr
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -16,6 +17,14 @@ type Repository interface {
 	ListByUser(ctx context.Context, userID string) ([]QRCode, error)
 	Update(ctx context.Context, qr *QRCode) error
 	Delete(ctx context.Context, id, userID string) error
+
+	// SetLimits stores the code's limits, resetScans restarts the count
+	SetLimits(ctx context.Context, id, userID string, limits Limits, resetScans bool) error
+	// ClaimScan counts a scan if the code is below max_scans and reports
+	// whether it was; concurrent scans can't exceed the cap
+	ClaimScan(ctx context.Context, id string) (bool, error)
+	// SetPassword stores the bcrypt hash scanners must match, "" removes it
+	SetPassword(ctx context.Context, id, userID, hash string) error
 }
 
 type repository struct {
@@ -39,6 +48,7 @@ func (r *repository) Create(ctx context.Context, qr *QRCode) error {
 			project_id,
 			name,
 			qr_type,
+			symbology,
 			short_code,
 			target_url,
 			design_json,
@@ -46,13 +56,14 @@ func (r *repository) Create(ctx context.Context, qr *QRCode) error {
 			created_at,
 			updated_at
 		)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
 	`,
 		qr.ID,
 		qr.UserID,
 		qr.ProjectID,
 		qr.Name,
 		qr.QRType,
+		qr.Symbology,
 		qr.ShortCode,
 		qr.TargetURL,
 		qr.DesignJSON,
@@ -75,33 +86,52 @@ func (r *repository) GetByID(ctx context.Context, id, userID string) (*QRCode, e
 			project_id,
 			name,
 			qr_type,
+			symbology,
 			short_code,
 			target_url,
 			design_json,
 			is_active,
 			created_at,
-			updated_at
+			updated_at,
+			valid_from,
+			valid_until,
+			max_scans,
+			scan_count,
+			fallbacks,
+			COALESCE(password_hash, '')
 		FROM qr_codes
 		WHERE id = $1 AND user_id = $2
 		LIMIT 1
 	`, id, userID)
 
 	var qr QRCode
+	var fallbacks []byte
 	if err := row.Scan(
 		&qr.ID,
 		&qr.UserID,
 		&qr.ProjectID,
 		&qr.Name,
 		&qr.QRType,
+		&qr.Symbology,
 		&qr.ShortCode,
 		&qr.TargetURL,
 		&qr.DesignJSON,
 		&qr.IsActive,
 		&qr.CreatedAt,
 		&qr.UpdatedAt,
+		&qr.ValidFrom,
+		&qr.ValidUntil,
+		&qr.MaxScans,
+		&qr.ScanCount,
+		&fallbacks,
+		&qr.PasswordHash,
 	); err != nil {
 		return nil, err
 	}
+	if err := decodeFallbacks(fallbacks, &qr); err != nil {
+		return nil, err
+	}
+	qr.PasswordProtected = qr.PasswordHash != ""
 
 	return &qr, nil
 }
@@ -114,33 +144,52 @@ func (r *repository) GetByShortCode(ctx context.Context, code string) (*QRCode,
 			project_id,
 			name,
 			qr_type,
+			symbology,
 			short_code,
 			target_url,
 			design_json,
 			is_active,
 			created_at,
-			updated_at
+			updated_at,
+			valid_from,
+			valid_until,
+			max_scans,
+			scan_count,
+			fallbacks,
+			COALESCE(password_hash, '')
 		FROM qr_codes
 		WHERE short_code = $1
 		LIMIT 1
 	`, code)
 
 	var qr QRCode
+	var fallbacks []byte
 	if err := row.Scan(
 		&qr.ID,
 		&qr.UserID,
 		&qr.ProjectID,
 		&qr.Name,
 		&qr.QRType,
+		&qr.Symbology,
 		&qr.ShortCode,
 		&qr.TargetURL,
 		&qr.DesignJSON,
 		&qr.IsActive,
 		&qr.CreatedAt,
 		&qr.UpdatedAt,
+		&qr.ValidFrom,
+		&qr.ValidUntil,
+		&qr.MaxScans,
+		&qr.ScanCount,
+		&fallbacks,
+		&qr.PasswordHash,
 	); err != nil {
 		return nil, err
 	}
+	if err := decodeFallbacks(fallbacks, &qr); err != nil {
+		return nil, err
+	}
+	qr.PasswordProtected = qr.PasswordHash != ""
 
 	return &qr, nil
 }
@@ -153,12 +202,19 @@ func (r *repository) ListByUser(ctx context.Context, userID string) ([]QRCode, e
 			project_id,
 			name,
 			qr_type,
+			symbology,
 			short_code,
 			target_url,
 			design_json,
 			is_active,
 			created_at,
-			updated_at
+			updated_at,
+			valid_from,
+			valid_until,
+			max_scans,
+			scan_count,
+			fallbacks,
+			COALESCE(password_hash, '')
 		FROM qr_codes
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -171,21 +227,33 @@ func (r *repository) ListByUser(ctx context.Context, userID string) ([]QRCode, e
 	var out []QRCode
 	for rows.Next() {
 		var qr QRCode
+		var fallbacks []byte
 		if err := rows.Scan(
 			&qr.ID,
 			&qr.UserID,
 			&qr.ProjectID,
 			&qr.Name,
 			&qr.QRType,
+			&qr.Symbology,
 			&qr.ShortCode,
 			&qr.TargetURL,
 			&qr.DesignJSON,
 			&qr.IsActive,
 			&qr.CreatedAt,
 			&qr.UpdatedAt,
+			&qr.ValidFrom,
+			&qr.ValidUntil,
+			&qr.MaxScans,
+			&qr.ScanCount,
+			&fallbacks,
+			&qr.PasswordHash,
 		); err != nil {
 			return nil, err
 		}
+		if err := decodeFallbacks(fallbacks, &qr); err != nil {
+			return nil, err
+		}
+		qr.PasswordProtected = qr.PasswordHash != ""
 		out = append(out, qr)
 	}
 	return out, nil
@@ -210,10 +278,64 @@ func (r *repository) Delete(ctx context.Context, id, userID string) error {
 func (r *repository) Update(ctx context.Context, qr *QRCode) error {
 	query := `
 		UPDATE qr_codes 
-		SET name=$1, target_url=$2, design_json=$3, updated_at=now()
-		WHERE id=$4 AND user_id=$5
+		SET name=$1, target_url=$2, design_json=$3, symbology=$4, qr_type=$5, updated_at=now()
+		WHERE id=$6 AND user_id=$7
 	`
-	cmd, err := r.pg.Exec(ctx, query, qr.Name, qr.TargetURL, qr.DesignJSON, qr.ID, qr.UserID)
+	cmd, err := r.pg.Exec(ctx, query, qr.Name, qr.TargetURL, qr.DesignJSON, qr.Symbology, qr.QRType, qr.ID, qr.UserID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return errors.New("qr not found or permission denied")
+	}
+	return nil
+}
+
+func (r *repository) SetLimits(ctx context.Context, id, userID string, limits Limits, resetScans bool) error {
+	fallbacks, err := json.Marshal(limits.Fallbacks)
+	if err != nil {
+		return err
+	}
+	cmd, err := r.pg.Exec(ctx, `
+		UPDATE qr_codes
+		SET valid_from=$1, valid_until=$2, max_scans=$3, fallbacks=$4,
+		    scan_count = CASE WHEN $5 THEN 0 ELSE scan_count END, updated_at=now()
+		WHERE id=$6 AND user_id=$7
+	`, limits.ValidFrom, limits.ValidUntil, limits.MaxScans, fallbacks, resetScans, id, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return errors.New("qr not found or permission denied")
+	}
+	return nil
+}
+
+func (r *repository) ClaimScan(ctx context.Context, id string) (bool, error) {
+	cmd, err := r.pg.Exec(ctx, `
+		UPDATE qr_codes SET scan_count = scan_count + 1
+		WHERE id=$1 AND (max_scans IS NULL OR scan_count < max_scans)
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() == 1, nil
+}
+
+// decodeFallbacks reads the fallbacks column, NULL on rows from before
+// limits existed
+func decodeFallbacks(raw []byte, qr *QRCode) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, &qr.Fallbacks)
+}
+
+func (r *repository) SetPassword(ctx context.Context, id, userID, hash string) error {
+	cmd, err := r.pg.Exec(ctx, `
+		UPDATE qr_codes SET password_hash=NULLIF($1, ''), updated_at=now()
+		WHERE id=$2 AND user_id=$3
+	`, hash, id, userID)
 	if err != nil {
 		return err
 	}
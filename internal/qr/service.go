@@ -1,38 +1,107 @@
 package qr
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
 	"math/big"
-	"os"
 	"strings"
 	"time"
 
+	"qr-saas/internal/assets"
 	"qr-saas/internal/qr/render"
+	"qr-saas/internal/scenes"
 
 	"github.com/google/uuid"
 )
 
 type Service interface {
-	CreateDynamicURL(ctx context.Context, userID, name, targetURL string, qrType string, design any) (*QRCode, error)
-	GenerateQRImage(ctx context.Context, qrID, userID, scene string) ([]byte, error)
+	CreateDynamicURL(ctx context.Context, userID, name, targetURL string, qrType, symbology string, design any, enforceScannable bool) (*QRCode, error)
+	GenerateQRImage(ctx context.Context, qrID, userID string, opts ImageOptions) (*RenderedImage, error)
+	ValidateQR(ctx context.Context, qrID, userID string, sceneID string) (*render.ScanReport, error)
+	RenderSheet(ctx context.Context, userID string, codes []QRCode, layout render.SheetLayout, caption string, outlines bool) ([]byte, error)
 	ListByUser(ctx context.Context, userID string) ([]QRCode, error)
 	GetQR(ctx context.Context, id, userID string) (*QRCode, error)
-	UpdateQR(ctx context.Context, id, userID, name, targetURL string, design any) (*QRCode, error)
+	UpdateQR(ctx context.Context, id, userID, name, targetURL, symbology string, design any, enforceScannable bool) (*QRCode, error)
+	// SetLimits replaces the validity window, scan cap and fallbacks of a
+	// dynamic code
+	SetLimits(ctx context.Context, id, userID string, req SetLimitsRequest) (*QRCode, error)
+	// SetPassword asks scanners of a dynamic code for a password before
+	// redirecting, "" lifts it
+	SetPassword(ctx context.Context, id, userID, password string) (*QRCode, error)
 	Delete(ctx context.Context, id, userID string) error
 }
 
 type service struct {
 	repo    Repository
 	baseURL string
+	scenes  scenes.Service
+	assets  assets.Service
+	cache   ImageCache
 }
 
-func NewService(repo Repository, baseURL string) Service {
-	return &service{repo: repo, baseURL: baseURL}
+// NewService wires the QR service; cache may be nil to render every time
+func NewService(repo Repository, baseURL string, sceneSvc scenes.Service, assetSvc assets.Service, cache ImageCache) Service {
+	if cache == nil {
+		cache = noopImageCache{}
+	}
+	return &service{repo: repo, baseURL: baseURL, scenes: sceneSvc, assets: assetSvc, cache: cache}
+}
+
+// defaultImageSize is the width in px of images served by GenerateQRImage
+// when the request doesn't ask for a size
+const defaultImageSize = 600
+
+// ErrSceneNeedsPNG is returned when a scene is requested in a vector format
+var ErrSceneNeedsPNG = errors.New("scenes are only available for png output")
+
+// ErrLinearNeedsStatic is returned for a dynamic code in a linear
+// symbology; EAN, UPC and Code 128 can't hold a short link.
+var ErrLinearNeedsStatic = errors.New("linear barcodes (ean13, upca, code128) can only be static")
+
+// ErrUnknownQRType is returned for a qr_type not in QRTypes
+var ErrUnknownQRType = errors.New("unknown qr_type")
+
+// QRTypes are the qr_type values a code can be created with: dynamic (or
+// url) codes redirect to target_url, static (or text) ones carry it as
+// their payload. Structured types (wifi, vcard, app, ...) are built and
+// validated by /api/qr/types on an existing code.
+var QRTypes = []string{"dynamic", "url", "static", "text"}
+
+func isKnownQRType(qrType string) bool {
+	for _, t := range QRTypes {
+		if t == qrType {
+			return true
+		}
+	}
+	return false
+}
+
+// NotScannableError rejects a save with enforce_scannable set when the
+// rendered design doesn't decode or scores below render.MinScanScore.
+type NotScannableError struct {
+	Report *render.ScanReport
+}
+
+func (e *NotScannableError) Error() string {
+	return fmt.Sprintf("design is not reliably scannable (score %d, need %d)", e.Report.Score, render.MinScanScore)
+}
+
+// ensureScannable runs the scan check for a save that asked for it
+func (s *service) ensureScannable(ctx context.Context, qr *QRCode) error {
+	report, err := s.verifyScannable(ctx, qr, nil)
+	if err != nil {
+		return err
+	}
+	if !report.Scannable || report.Score < render.MinScanScore {
+		return &NotScannableError{Report: report}
+	}
+	return nil
 }
 
 func GenerateShortCode(length int) (string, error) {
@@ -48,15 +117,27 @@ func GenerateShortCode(length int) (string, error) {
 	return string(b), nil
 }
 
-func (s *service) CreateDynamicURL(ctx context.Context, userID, name, targetURL string, qrType string, design any) (*QRCode, error) {
+func (s *service) CreateDynamicURL(ctx context.Context, userID, name, targetURL string, qrType, symbology string, design any, enforceScannable bool) (*QRCode, error) {
 	if targetURL == "" {
 		return nil, errors.New("target_url required")
 	}
 	if name == "" {
 		name = "My QR Code"
 	}
+	qrType = strings.ToLower(strings.TrimSpace(qrType))
+	if !isKnownQRType(qrType) {
+		return nil, fmt.Errorf("%w %q, use one of %s (structured types are set with /api/qr/types)", ErrUnknownQRType, qrType, strings.Join(QRTypes, ", "))
+	}
 
-	designJSON, _ := json.Marshal(design)
+	symbology, err := checkSymbology(symbology, qrType, targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	designJSON, err := s.designJSON(ctx, userID, design)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now().UTC()
 
 	finalQRType := qrType
@@ -67,7 +148,6 @@ func (s *service) CreateDynamicURL(ctx context.Context, userID, name, targetURL
 	}
 
 	var qr *QRCode
-	var err error
 
 	for i := 0; i < 3; i++ {
 		shortCode, errGen := GenerateShortCode(6)
@@ -81,14 +161,23 @@ func (s *service) CreateDynamicURL(ctx context.Context, userID, name, targetURL
 			ProjectID:   nil,
 			Name:        name,
 			QRType:      finalQRType,
+			Symbology:   symbology,
 			ShortCode:   shortCode,
 			TargetURL:   finalTargetURL,
-			DesignJSON:  string(designJSON),
+			DesignJSON:  designJSON,
 			IsActive:    true,
 			CreatedAt:   now,
 			UpdatedAt:   now,
 		}
 
+		// Short codes all have the same length, so checking the first
+		// candidate is enough
+		if enforceScannable && i == 0 {
+			if err := s.ensureScannable(ctx, qr); err != nil {
+				return nil, err
+			}
+		}
+
 		err = s.repo.Create(ctx, qr)
 		if err == nil {
 			break
@@ -102,44 +191,220 @@ func (s *service) CreateDynamicURL(ctx context.Context, userID, name, targetURL
 		return nil, fmt.Errorf("failed to create QR after retries: %w", err)
 	}
 
+	qr.Warnings = s.designWarnings(ctx, qr)
 	return qr, nil
 }
 
 // Updated struct to capture the Logo string (Base64)
 type DesignConfig struct {
-	Color   string `json:"color"`
-	BgColor string `json:"bgColor"`
-	Logo    string `json:"logo"` // Can be a Base64 string
+	Color           string `json:"color"`
+	BgColor         string `json:"bgColor"`
+	Logo            string `json:"logo"`            // Legacy inline Base64, moved to LogoAssetID on save
+	LogoAssetID     string `json:"logoAssetId"`     // Uploaded via /api/assets
+	ErrorCorrection string `json:"errorCorrection"` // L/M/Q/H, bumped to H when a logo is set
+	Margin          *int   `json:"margin"`          // Quiet zone in modules (default 4)
+
+	// Module & finder pattern ("eye") styling
+	ModuleShape   string `json:"moduleShape"`   // square, dots, rounded, fluid
+	EyeFrameShape string `json:"eyeFrameShape"` // square, rounded, circle
+	EyeBallShape  string `json:"eyeBallShape"`  // square, rounded, circle
+	EyeFrameColor string `json:"eyeFrameColor"`
+	EyeBallColor  string `json:"eyeBallColor"`
+
+	Gradient *render.Gradient `json:"gradient"` // Overrides color on the modules
+	Frame    *render.Frame    `json:"frame"`    // Border/banner with a CTA label
+	Caption  *render.Caption  `json:"caption"`  // Text under/over the code, see captionText
 }
 
-func (s *service) GenerateQRImage(ctx context.Context, qrID, userID, scene string) ([]byte, error) {
+func (s *service) GenerateQRImage(ctx context.Context, qrID, userID string, opts ImageOptions) (*RenderedImage, error) {
+	format, err := render.NormalizeFormat(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Size == 0 && opts.WidthMM == 0 {
+		opts.Size = defaultImageSize
+	}
+	size, err := render.SizeOptions{Pixels: opts.Size, DPI: opts.DPI, WidthMM: opts.WidthMM}.Resolve(format)
+	if err != nil {
+		return nil, err
+	}
+
+	scene, err := s.lookupScene(ctx, opts.Scene, format)
+	if err != nil {
+		return nil, err
+	}
+
 	qrData, err := s.repo.GetByID(ctx, qrID, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	contentToEncode, err := s.encodedContent(qrData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything that changes the output is in the key, so the client's
+	// ETag and the cache can be checked before touching the logo
+	etag := renderKey(qrData, contentToEncode, size, format, scene)
+	if etagMatches(opts.IfNoneMatch, etag) {
+		return &RenderedImage{ETag: etag, NotModified: true}, nil
+	}
+	if img, ok := s.cache.Get(ctx, etag); ok {
+		return &RenderedImage{Data: img, ETag: etag}, nil
+	}
+
+	renderOpts := s.designOptions(ctx, qrData, nil)
+	renderOpts.Format = format
+	renderOpts.Size = size.Pixels
+	renderOpts.DPI = size.DPI
+	renderOpts.WidthMM = size.WidthMM
+
+	img, err := renderScene(contentToEncode, renderOpts, scene)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(ctx, qrData.ID, etag, img)
+
+	return &RenderedImage{Data: img, ETag: etag}, nil
+}
+
+// etagMatches checks an If-None-Match header (a list, possibly weak
+// validators) against our ETag
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || strings.Trim(tag, `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateQR renders the code as a PNG (inside the scene, if one is
+// given) and decodes it again to score how reliably it scans.
+func (s *service) ValidateQR(ctx context.Context, qrID, userID string, sceneID string) (*render.ScanReport, error) {
+	scene, err := s.lookupScene(ctx, sceneID, render.FormatPNG)
+	if err != nil {
+		return nil, err
+	}
+
+	qrData, err := s.repo.GetByID(ctx, qrID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.verifyScannable(ctx, qrData, scene)
+}
+
+// verifyScannable is shared by ValidateQR and the enforce_scannable
+// check on save, which runs before the row is written.
+func (s *service) verifyScannable(ctx context.Context, qrData *QRCode, scene *scenes.Scene) (*render.ScanReport, error) {
+	content, err := s.encodedContent(qrData)
+	if err != nil {
+		return nil, err
+	}
+
+	renderOpts := s.designOptions(ctx, qrData, nil)
+	renderOpts.Format = render.FormatPNG
+
+	img, err := renderScene(content, renderOpts, scene)
+	if err != nil {
+		return nil, err
+	}
+	return render.VerifyScannability(img, content, renderOpts)
+}
+
+// RenderSheet renders codes onto label sheets as one PDF. caption, when
+// set, replaces each design's caption (placeholders expanded per code).
+func (s *service) RenderSheet(ctx context.Context, userID string, codes []QRCode, layout render.SheetLayout, caption string, outlines bool) ([]byte, error) {
+	logos := map[string]image.Image{}
+	labels := make([]render.Label, 0, len(codes))
+	for i := range codes {
+		qrData := &codes[i]
+		if qrData.UserID != userID {
+			return nil, fmt.Errorf("qr %s: access denied", qrData.ID)
+		}
+		content, err := s.encodedContent(qrData)
+		if err != nil {
+			return nil, fmt.Errorf("qr %q: %w", qrData.Name, err)
+		}
+
+		opts := s.designOptions(ctx, qrData, logos)
+		if caption != "" {
+			opts.Caption = s.caption(qrData, &render.Caption{Text: caption})
+		}
+		labels = append(labels, render.Label{Content: content, Options: opts})
+	}
+	return render.RenderSheet(labels, layout, outlines)
+}
+
+// lookupScene resolves the ?scene= value; "" and "plain" mean none
+func (s *service) lookupScene(ctx context.Context, sceneID, format string) (*scenes.Scene, error) {
+	if sceneID == "" || sceneID == "plain" {
+		return nil, nil
+	}
+	if format != render.FormatPNG {
+		return nil, ErrSceneNeedsPNG
+	}
+	return s.scenes.Get(ctx, sceneID)
+}
+
+// checkSymbology normalizes the symbology and checks it can carry the
+// code: linear symbologies only hold static content, which has to fit
+// (e.g. 12 digits for EAN-13).
+func checkSymbology(symbology, qrType, targetURL string) (string, error) {
+	symbology, err := render.NormalizeSymbology(symbology)
+	if err != nil {
+		return "", err
+	}
+	if !render.IsLinear(symbology) {
+		return symbology, nil
+	}
+	if IsDynamic(qrType) {
+		return "", ErrLinearNeedsStatic
+	}
+	if err := render.ValidateContent(symbology, targetURL); err != nil {
+		return "", err
+	}
+	return symbology, nil
+}
+
+// IsDynamic reports whether codes of qrType encode their short link and
+// redirect (app codes pick the destination per device)
+func IsDynamic(qrType string) bool {
+	return qrType == "dynamic" || qrType == "url" || qrType == "app"
+}
+
+// encodedContent is what the symbol carries: the short link for
+// dynamic codes, the raw payload for static ones.
+func (s *service) encodedContent(qrData *QRCode) (string, error) {
 	if qrData.ShortCode == "" {
-		return nil, errors.New("qr code has no short code")
+		return "", errors.New("qr code has no short code")
 	}
 
 	var contentToEncode string
-	if qrData.QRType == "dynamic" || qrData.QRType == "url" {
+	if IsDynamic(qrData.QRType) {
 		contentToEncode = fmt.Sprintf("%s/r/%s", s.baseURL, qrData.ShortCode)
 	} else {
 		contentToEncode = qrData.TargetURL
 	}
 
 	if contentToEncode == "" {
-		return nil, errors.New("cannot generate QR for empty content")
+		return "", errors.New("cannot generate QR for empty content")
 	}
+	return contentToEncode, nil
+}
 
-	// ---------------------------------------------------------
-	// EXTRACT DESIGN (Colors & Logo)
-	// ---------------------------------------------------------
+// designOptions turns the stored design JSON into render options. The
+// logo comes from the asset store; legacy rows may still carry it
+// inline as base64, which is decoded in memory. logos, when set, keeps
+// decoded assets across calls so codes sharing a logo share the image.
+func (s *service) designOptions(ctx context.Context, qrData *QRCode, logos map[string]image.Image) render.RenderOptions {
 	var design DesignConfig
 	fgColor := "#000000"
 	bgColor := "#ffffff"
-	var logoPath string
+	var logo image.Image
 
 	if qrData.DesignJSON != "" {
 		if err := json.Unmarshal([]byte(qrData.DesignJSON), &design); err == nil {
@@ -150,49 +415,117 @@ func (s *service) GenerateQRImage(ctx context.Context, qrID, userID, scene strin
 				bgColor = design.BgColor
 			}
 
-			// 🔥 HANDLE LOGO (Base64 -> Temp File)
-			if design.Logo != "" && len(design.Logo) > 20 {
+			if img, ok := logos[design.LogoAssetID]; ok && design.LogoAssetID != "" {
+				logo = img
+			} else if design.LogoAssetID != "" {
+				img, err := s.assets.LoadImage(ctx, qrData.UserID, design.LogoAssetID)
+				if err != nil {
+					// Render without the logo rather than failing the image
+					fmt.Printf("⚠️ QR %s logo asset %s: %v\n", qrData.ID, design.LogoAssetID, err)
+				}
+				logo = img
+				if logos != nil {
+					logos[design.LogoAssetID] = img
+				}
+			} else if design.Logo != "" && len(design.Logo) > 20 {
 				// Remove data URI prefix if present (e.g. "data:image/png;base64,")
 				parts := strings.Split(design.Logo, ",")
 				b64Data := parts[len(parts)-1]
 
-				// Decode
-				decoded, decodeErr := base64.StdEncoding.DecodeString(b64Data)
-				if decodeErr == nil {
-					// Write to temp file
-					tmpFile, tmpErr := os.CreateTemp("", "qr-logo-*.png")
-					if tmpErr == nil {
-						// Clean up file after function exits (or shortly after)
-						defer os.Remove(tmpFile.Name())
-						
-						tmpFile.Write(decoded)
-						tmpFile.Close()
-						logoPath = tmpFile.Name()
-					}
+				if decoded, err := base64.StdEncoding.DecodeString(b64Data); err == nil {
+					logo, _, _ = image.Decode(bytes.NewReader(decoded))
 				}
 			}
 		}
 	}
 
-	// 3. Render QR with Options
-	qrBytes, err := render.RenderQRWithLogo(contentToEncode, render.RenderOptions{
-		Size:            600,
+	return render.RenderOptions{
+		Size:            defaultImageSize,
 		Color:           fgColor,
 		BackgroundColor: bgColor,
-		LogoPath:        logoPath, // Pass the temp file path
-	})
+		Logo:            logo,
+		ErrorCorrection: design.ErrorCorrection,
+		Margin:          design.Margin,
+		ModuleShape:     design.ModuleShape,
+		EyeFrameShape:   design.EyeFrameShape,
+		EyeBallShape:    design.EyeBallShape,
+		EyeFrameColor:   design.EyeFrameColor,
+		EyeBallColor:    design.EyeBallColor,
+		Gradient:        design.Gradient,
+		Frame:           design.Frame,
+		Caption:         s.caption(qrData, design.Caption),
+		Symbology:       qrData.Symbology,
+	}
+}
+
+// designWarnings are the borderline contrasts of a saved design. Designs
+// below the minimum are refused when rendered.
+func (s *service) designWarnings(ctx context.Context, qrData *QRCode) []string {
+	warnings, _ := render.CheckContrast(s.designOptions(ctx, qrData, nil))
+	return warnings
+}
+
+// caption returns a copy of c with its placeholders expanded
+func (s *service) caption(qrData *QRCode, c *render.Caption) *render.Caption {
+	if c == nil {
+		return nil
+	}
+	out := *c
+	out.Text = s.captionText(qrData, c.Text)
+	return &out
+}
+
+// captionText expands {short_url} (without the scheme, it's meant to be
+// typed), {short_code} and {name} in a caption.
+func (s *service) captionText(qrData *QRCode, text string) string {
+	shortURL := fmt.Sprintf("%s/r/%s", s.baseURL, qrData.ShortCode)
+	shortURL = strings.TrimPrefix(strings.TrimPrefix(shortURL, "https://"), "http://")
+	return strings.NewReplacer(
+		"{short_url}", shortURL,
+		"{short_code}", qrData.ShortCode,
+		"{name}", qrData.Name,
+	).Replace(text)
+}
+
+// designJSON serializes a design for storage. Inline base64 logos are
+// moved into the asset store first so rows only carry a logoAssetId,
+// and a referenced asset has to belong to the user.
+func (s *service) designJSON(ctx context.Context, userID string, design any) (string, error) {
+	raw, err := json.Marshal(design)
+	if err != nil {
+		return "", err
+	}
+
+	var m map[string]interface{}
+	if json.Unmarshal(raw, &m) != nil || m == nil {
+		return string(raw), nil
+	}
+
+	if err := s.assets.ExtractInlineLogo(ctx, userID, m); err != nil {
+		return "", err
+	}
+	if id, _ := m["logoAssetId"].(string); id != "" {
+		if _, err := s.assets.Get(ctx, userID, id); err != nil {
+			return "", err
+		}
+	}
+
+	raw, err = json.Marshal(m)
+	return string(raw), err
+}
+
+// renderScene renders the code and composites it into scene, if any
+func renderScene(content string, opts render.RenderOptions, scene *scenes.Scene) ([]byte, error) {
+	qrBytes, err := render.RenderQRWithLogo(content, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	if scene == "person_pizza" {
+	if scene != nil {
 		return render.ComposeQROnBackground(render.CompositeOptions{
-			BackgroundPath: "assets/person_pizza.png",
+			BackgroundPath: scene.BackgroundPath,
 			QRBytes:        qrBytes,
-			PosX:           200,
-			PosY:           150,
-			Width:          250,
-			Height:         250,
+			Quad:           &scene.Quad,
 		})
 	}
 
@@ -204,14 +537,18 @@ func (s *service) ListByUser(ctx context.Context, userID string) ([]QRCode, erro
 }
 
 func (s *service) Delete(ctx context.Context, id, userID string) error {
-	return s.repo.Delete(ctx, id, userID)
+	if err := s.repo.Delete(ctx, id, userID); err != nil {
+		return err
+	}
+	s.cache.Invalidate(ctx, id)
+	return nil
 }
 // Implementation
 func (s *service) GetQR(ctx context.Context, id, userID string) (*QRCode, error) {
     return s.repo.GetByID(ctx, id, userID)
 }
 
-func (s *service) UpdateQR(ctx context.Context, id, userID, name, targetURL string, design any) (*QRCode, error) {
+func (s *service) UpdateQR(ctx context.Context, id, userID, name, targetURL, symbology string, design any, enforceScannable bool) (*QRCode, error) {
     // 1. Fetch existing to ensure ownership
     qr, err := s.repo.GetByID(ctx, id, userID)
     if err != nil { return nil, err }
@@ -219,13 +556,31 @@ func (s *service) UpdateQR(ctx context.Context, id, userID, name, targetURL stri
     // 2. Update fields
     qr.Name = name
     qr.TargetURL = targetURL // Stores raw content for static, or URL for dynamic
+    if symbology == "" {
+        symbology = qr.Symbology // keep the current one
+    }
+    qr.Symbology, err = checkSymbology(symbology, qr.QRType, qr.TargetURL)
+    if err != nil {
+        return nil, err
+    }
     
-    designJSON, _ := json.Marshal(design)
-    qr.DesignJSON = string(designJSON)
+    designJSON, err := s.designJSON(ctx, userID, design)
+    if err != nil {
+        return nil, err
+    }
+    qr.DesignJSON = designJSON
+
+    if enforceScannable {
+        if err := s.ensureScannable(ctx, qr); err != nil {
+            return nil, err
+        }
+    }
     
     // 3. Save
     if err := s.repo.Update(ctx, qr); err != nil {
         return nil, err
     }
+    s.cache.Invalidate(ctx, qr.ID)
+    qr.Warnings = s.designWarnings(ctx, qr)
     return qr, nil
 }
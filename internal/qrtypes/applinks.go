@@ -0,0 +1,60 @@
+package qrtypes
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Normalized checks the store links and fills in the web fallback. Store
+// links are https URLs, checked against the store hosts so the App Store
+// link can't end up on Android.
+func (a AppLinksData) Normalized() (AppLinksData, error) {
+	a.IOSURL = strings.TrimSpace(a.IOSURL)
+	a.AndroidURL = strings.TrimSpace(a.AndroidURL)
+	a.WebURL = strings.TrimSpace(a.WebURL)
+	if a.IOSURL == "" && a.AndroidURL == "" {
+		return a, fmt.Errorf("%w: ios_url or android_url is required", ErrInvalidPayload)
+	}
+	if a.IOSURL != "" && !isStoreURL(a.IOSURL, "apps.apple.com", "itunes.apple.com") {
+		return a, fmt.Errorf("%w: ios_url must be an https://apps.apple.com link", ErrInvalidPayload)
+	}
+	if a.AndroidURL != "" && !isStoreURL(a.AndroidURL, "play.google.com") {
+		return a, fmt.Errorf("%w: android_url must be an https://play.google.com link", ErrInvalidPayload)
+	}
+	switch {
+	case a.WebURL != "" && !isWebURL(a.WebURL):
+		return a, fmt.Errorf("%w: web_url must be an http(s) URL", ErrInvalidPayload)
+	case a.WebURL == "" && a.IOSURL != "":
+		a.WebURL = a.IOSURL
+	case a.WebURL == "":
+		a.WebURL = a.AndroidURL
+	}
+	return a, nil
+}
+
+func isStoreURL(s string, hosts ...string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(u.Host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// StoreURL is the store link for a device OS, as mileusna/useragent
+// names it ("iOS", "Android", ...), or "" where the code's target_url
+// (the web link) applies
+func (a AppLinksData) StoreURL(os string) string {
+	switch os {
+	case "iOS":
+		return a.IOSURL
+	case "Android":
+		return a.AndroidURL
+	}
+	return ""
+}
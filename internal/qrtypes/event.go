@@ -0,0 +1,402 @@
+package qrtypes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezones resolve on hosts without a zoneinfo database
+)
+
+// Time layouts accepted for event start and end, local to the timezone
+// unless they carry an offset
+var eventLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", time.RFC3339}
+
+const (
+	eventDate     = "2006-01-02"
+	icalDate      = "20060102"
+	icalLocalTime = "20060102T150405"
+	icalUTCTime   = "20060102T150405Z"
+)
+
+// event is a validated EventData, ready to encode
+type event struct {
+	start, end time.Time
+	tz         string
+	loc        *time.Location
+	rrule      string
+	v          EventData
+}
+
+// Payload encodes the event as a bare VEVENT, which phone cameras offer
+// to add to the calendar. There is no room for a VTIMEZONE, so times are
+// UTC; the .ics download keeps the timezone of recurring events.
+func (e EventData) Payload() (string, error) {
+	ev, err := e.validate()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(ev.vevent(nil, false), "\r\n"), nil
+}
+
+// ICS is the event as an .ics file; uid identifies it across downloads so
+// calendars update rather than duplicate it
+func (e EventData) ICS(uid string) ([]byte, error) {
+	ev, err := e.validate()
+	if err != nil {
+		return nil, err
+	}
+	extra := []string{
+		"UID:" + icalEscape(uid),
+		"DTSTAMP:" + time.Now().UTC().Format(icalUTCTime),
+	}
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//qr-saas//events//EN", "CALSCALE:GREGORIAN"}
+	if ev.zoned() {
+		lines = append(lines, vtimezone(ev.tz, ev.loc, ev.start.Year()-1)...)
+	}
+	lines = append(lines, ev.vevent(extra, true)...)
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(icalFold(l))
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// When describes the event time for people, e.g.
+// "Thu, 5 Nov 2026, 09:00 - 10:00 (Asia/Kolkata), repeats weekly"
+func (e EventData) When() (string, error) {
+	ev, err := e.validate()
+	if err != nil {
+		return "", err
+	}
+	const day = "Mon, 2 Jan 2006"
+
+	var when string
+	switch last := ev.end.AddDate(0, 0, -1); {
+	case e.AllDay && last.Equal(ev.start):
+		when = ev.start.Format(day)
+	case e.AllDay:
+		when = ev.start.Format(day) + " - " + last.Format(day)
+	case ev.end.YearDay() == ev.start.YearDay() && ev.end.Year() == ev.start.Year():
+		when = ev.start.Format(day+", 15:04") + " - " + ev.end.Format("15:04") + " (" + ev.tz + ")"
+	default:
+		when = ev.start.Format(day+", 15:04") + " - " + ev.end.Format(day+", 15:04") + " (" + ev.tz + ")"
+	}
+
+	if ev.rrule != "" {
+		for _, part := range strings.Split(ev.rrule, ";") {
+			if freq, ok := strings.CutPrefix(part, "FREQ="); ok {
+				when += ", repeats " + strings.ToLower(freq)
+			}
+		}
+	}
+	return when, nil
+}
+
+func (e EventData) validate() (*event, error) {
+	ev := &event{v: e}
+	if strings.TrimSpace(e.Title) == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidPayload)
+	}
+
+	ev.tz = strings.TrimSpace(e.Timezone)
+	if ev.tz == "" {
+		ev.tz = "UTC"
+	}
+	loc, err := time.LoadLocation(ev.tz)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidPayload, ev.tz)
+	}
+	ev.loc = loc
+
+	if ev.start, err = parseEventTime(e.Start, e.AllDay, loc); err != nil {
+		return nil, fmt.Errorf("%w: start: %v", ErrInvalidPayload, err)
+	}
+	switch {
+	case strings.TrimSpace(e.End) != "":
+		if ev.end, err = parseEventTime(e.End, e.AllDay, loc); err != nil {
+			return nil, fmt.Errorf("%w: end: %v", ErrInvalidPayload, err)
+		}
+		if e.AllDay {
+			// The end date is inclusive in requests, exclusive in iCalendar
+			ev.end = ev.end.AddDate(0, 0, 1)
+		}
+	case e.AllDay:
+		ev.end = ev.start.AddDate(0, 0, 1)
+	default:
+		ev.end = ev.start.Add(time.Hour)
+	}
+	if !ev.end.After(ev.start) {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidPayload)
+	}
+
+	if e.URL != "" && !isWebURL(e.URL) {
+		return nil, fmt.Errorf("%w: url must be an http(s) URL", ErrInvalidPayload)
+	}
+	if e.RRule != "" {
+		if ev.rrule, err = normalizeRRule(e.RRule); err != nil {
+			return nil, err
+		}
+		// UNTIL takes the type of DTSTART, and is UTC when DTSTART is a
+		// time: in the payload it is UTC, in the .ics it has a TZID
+		until := rrulePart(ev.rrule, "UNTIL")
+		if _, err := time.Parse(icalDate, until); err == nil && !e.AllDay {
+			return nil, fmt.Errorf("%w: rrule UNTIL must be a UTC time like 20060102T150405Z", ErrInvalidPayload)
+		}
+		if _, err := time.Parse(icalUTCTime, until); err == nil && e.AllDay {
+			return nil, fmt.Errorf("%w: rrule UNTIL of an all day event must be a date like 20060102", ErrInvalidPayload)
+		}
+	}
+	return ev, nil
+}
+
+// zoned tells if the .ics keeps the event's timezone: recurring events
+// do, so they don't drift an hour over DST
+func (ev *event) zoned() bool {
+	return ev.rrule != "" && !ev.v.AllDay && ev.tz != "UTC"
+}
+
+func parseEventTime(s string, allDay bool, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("is required")
+	}
+	if allDay {
+		t, err := time.ParseInLocation(eventDate, s, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("all day events take a date like 2006-01-02")
+		}
+		return t, nil
+	}
+	for _, layout := range eventLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.In(loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("use a time like 2006-01-02T15:04")
+}
+
+// vevent renders the VEVENT lines, extra goes right after BEGIN. Times
+// are UTC unless zoned, which needs the VTIMEZONE of the event's tz.
+func (ev *event) vevent(extra []string, zoned bool) []string {
+	lines := append([]string{"BEGIN:VEVENT"}, extra...)
+	add := func(prop, value string) {
+		if value != "" {
+			lines = append(lines, prop+":"+value)
+		}
+	}
+
+	add("SUMMARY", icalEscape(strings.TrimSpace(ev.v.Title)))
+	switch {
+	case ev.v.AllDay:
+		add("DTSTART;VALUE=DATE", ev.start.Format(icalDate))
+		add("DTEND;VALUE=DATE", ev.end.Format(icalDate))
+	case zoned && ev.zoned():
+		add("DTSTART;TZID="+ev.tz, ev.start.Format(icalLocalTime))
+		add("DTEND;TZID="+ev.tz, ev.end.Format(icalLocalTime))
+	default:
+		add("DTSTART", ev.start.UTC().Format(icalUTCTime))
+		add("DTEND", ev.end.UTC().Format(icalUTCTime))
+	}
+	add("RRULE", ev.rrule)
+	add("LOCATION", icalEscape(strings.TrimSpace(ev.v.Location)))
+	add("DESCRIPTION", icalEscape(strings.TrimSpace(ev.v.Description)))
+	add("URL", ev.v.URL)
+	return append(lines, "END:VEVENT")
+}
+
+// rruleParts are the RRULE parts accepted, with a check of their value
+var rruleParts = map[string]func(string) bool{
+	"FREQ":       isFreq,
+	"INTERVAL":   func(v string) bool { return intIn(v, 1, 1000) },
+	"COUNT":      func(v string) bool { return intIn(v, 1, 1000) },
+	"UNTIL":      isUntil,
+	"BYDAY":      func(v string) bool { return listOf(v, isByDay) },
+	"BYMONTHDAY": func(v string) bool { return listOf(v, signedIn(1, 31)) },
+	"BYMONTH":    func(v string) bool { return listOf(v, func(m string) bool { return intIn(m, 1, 12) }) },
+	"BYSETPOS":   func(v string) bool { return listOf(v, signedIn(1, 366)) },
+	"WKST":       isWeekday,
+}
+
+// normalizeRRule checks a recurrence rule such as FREQ=WEEKLY;BYDAY=MO,WE
+// (an RRULE: prefix is fine) and returns it upper-cased without the prefix
+func normalizeRRule(rule string) (string, error) {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	rule = strings.TrimPrefix(rule, "RRULE:")
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		check, known := rruleParts[key]
+		if !ok || !known || seen[key] || !check(value) {
+			return "", fmt.Errorf("%w: invalid rrule part %q", ErrInvalidPayload, part)
+		}
+		seen[key] = true
+	}
+	if !seen["FREQ"] {
+		return "", fmt.Errorf("%w: rrule needs FREQ", ErrInvalidPayload)
+	}
+	if seen["COUNT"] && seen["UNTIL"] {
+		return "", fmt.Errorf("%w: rrule takes COUNT or UNTIL, not both", ErrInvalidPayload)
+	}
+	return rule, nil
+}
+
+// rrulePart is the value of key in a normalized rule, "" when not set
+func rrulePart(rule, key string) string {
+	for _, part := range strings.Split(rule, ";") {
+		if v, ok := strings.CutPrefix(part, key+"="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func intIn(s string, lo, hi int) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= lo && n <= hi
+}
+
+// signedIn checks a number, possibly negative (counted from the end),
+// whose size is within lo and hi
+func signedIn(lo, hi int) func(string) bool {
+	return func(s string) bool {
+		return intIn(strings.TrimPrefix(s, "-"), lo, hi)
+	}
+}
+
+func listOf(s string, check func(string) bool) bool {
+	for _, v := range strings.Split(s, ",") {
+		if !check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func isFreq(s string) bool {
+	switch s {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+		return true
+	}
+	return false
+}
+
+func isWeekday(s string) bool {
+	switch s {
+	case "MO", "TU", "WE", "TH", "FR", "SA", "SU":
+		return true
+	}
+	return false
+}
+
+// isByDay takes a weekday with an optional ordinal, e.g. MO, 1MO or -1FR
+func isByDay(s string) bool {
+	if len(s) < 2 || !isWeekday(s[len(s)-2:]) {
+		return false
+	}
+	n := strings.TrimPrefix(strings.TrimPrefix(s[:len(s)-2], "+"), "-")
+	return n == "" || intIn(n, 1, 53)
+}
+
+func isUntil(s string) bool {
+	if _, err := time.Parse(icalDate, s); err == nil {
+		return true
+	}
+	_, err := time.Parse(icalUTCTime, s)
+	return err == nil
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// icalDays are the weekdays as RRULE writes them, by time.Weekday
+var icalDays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// vtimezone describes loc from year on as a VTIMEZONE. The transitions of
+// that year repeat yearly when there are two of them (DST); otherwise
+// they are one-off changes, or the zone has a single fixed offset.
+func vtimezone(tzid string, loc *time.Location, year int) []string {
+	type change struct {
+		at       time.Time // first instant of the new offset
+		from, to int
+	}
+	var changes []change
+	t := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, prev := t.In(loc).Zone()
+	for end := t.AddDate(1, 0, 0); t.Before(end); t = t.Add(time.Hour) {
+		if _, off := t.In(loc).Zone(); off != prev {
+			at := t.Add(-time.Hour)
+			for _, o := at.In(loc).Zone(); o == prev; _, o = at.In(loc).Zone() {
+				at = at.Add(time.Minute)
+			}
+			changes = append(changes, change{at, prev, off})
+			prev = off
+		}
+	}
+
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + tzid}
+	if len(changes) == 0 {
+		name, off := t.In(loc).Zone()
+		return append(lines, "BEGIN:STANDARD", "DTSTART:19700101T000000",
+			"TZOFFSETFROM:"+icalOffset(off), "TZOFFSETTO:"+icalOffset(off),
+			"TZNAME:"+name, "END:STANDARD", "END:VTIMEZONE")
+	}
+	for _, c := range changes {
+		kind := "STANDARD"
+		if c.at.In(loc).IsDST() {
+			kind = "DAYLIGHT"
+		}
+		// DTSTART is the wall time of the change before it happens
+		wall := c.at.Add(time.Duration(c.from) * time.Second).UTC()
+		name, _ := c.at.In(loc).Zone()
+		lines = append(lines, "BEGIN:"+kind, "DTSTART:"+wall.Format(icalLocalTime),
+			"TZOFFSETFROM:"+icalOffset(c.from), "TZOFFSETTO:"+icalOffset(c.to), "TZNAME:"+name)
+		if len(changes) == 2 {
+			// The nth (or last) weekday of the month, as DST rules go
+			n := strconv.Itoa((wall.Day()-1)/7 + 1)
+			if wall.AddDate(0, 0, 7).Month() != wall.Month() {
+				n = "-1"
+			}
+			lines = append(lines, fmt.Sprintf("RRULE:FREQ=YEARLY;BYMONTH=%d;BYDAY=%s%s", wall.Month(), n, icalDays[wall.Weekday()]))
+		}
+		lines = append(lines, "END:"+kind)
+	}
+	return append(lines, "END:VTIMEZONE")
+}
+
+// icalOffset formats a UTC offset in seconds as +HHMM
+func icalOffset(secs int) string {
+	sign := "+"
+	if secs < 0 {
+		sign, secs = "-", -secs
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, secs/3600, secs%3600/60)
+}
+
+// icalFold splits lines longer than 75 octets as RFC 5545 asks, without
+// cutting a UTF-8 sequence
+func icalFold(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,191 @@
+package qrtypes
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEventPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		data EventData
+		want []string // lines, joined with CRLF
+	}{
+		{
+			name: "escaped text in UTC",
+			data: EventData{
+				Title:       "Launch; v2, final",
+				Start:       "2026-11-05T09:00",
+				Timezone:    "Asia/Kolkata",
+				Location:    `Hall A\B`,
+				Description: "Line 1\r\nLine 2\rLine 3\nLine 4",
+				URL:         "https://example.com/live?a=1,2",
+			},
+			want: []string{
+				"BEGIN:VEVENT",
+				`SUMMARY:Launch\; v2\, final`,
+				"DTSTART:20261105T033000Z",
+				"DTEND:20261105T043000Z",
+				`LOCATION:Hall A\\B`,
+				`DESCRIPTION:Line 1\nLine 2\nLine 3\nLine 4`,
+				"URL:https://example.com/live?a=1,2",
+				"END:VEVENT",
+			},
+		},
+		{
+			name: "all day, end inclusive",
+			data: EventData{Title: "Holidays", Start: "2026-12-24", End: "2026-12-26", AllDay: true, RRule: "FREQ=YEARLY;UNTIL=20301224"},
+			want: []string{
+				"BEGIN:VEVENT",
+				"SUMMARY:Holidays",
+				"DTSTART;VALUE=DATE:20261224",
+				"DTEND;VALUE=DATE:20261227",
+				"RRULE:FREQ=YEARLY;UNTIL=20301224",
+				"END:VEVENT",
+			},
+		},
+		{
+			name: "recurring is UTC too",
+			data: EventData{Title: "Standup", Start: "2026-11-03T18:30", End: "2026-11-03T19:00", Timezone: "Europe/Berlin", RRule: "rrule:freq=weekly;byday=tu;count=6"},
+			want: []string{
+				"BEGIN:VEVENT",
+				"SUMMARY:Standup",
+				"DTSTART:20261103T173000Z",
+				"DTEND:20261103T180000Z",
+				"RRULE:FREQ=WEEKLY;BYDAY=TU;COUNT=6",
+				"END:VEVENT",
+			},
+		},
+		{
+			name: "offset wins over the timezone",
+			data: EventData{Title: "Call", Start: "2026-11-05T09:00:00-05:00", Timezone: "Europe/Berlin"},
+			want: []string{
+				"BEGIN:VEVENT",
+				"SUMMARY:Call",
+				"DTSTART:20261105T140000Z",
+				"DTEND:20261105T150000Z",
+				"END:VEVENT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.data.Payload()
+		want := strings.Join(tt.want, "\r\n")
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v\nwant %q", tt.name, got, err, want)
+		}
+	}
+}
+
+var dtstampRe = regexp.MustCompile(`DTSTAMP:\d{8}T\d{6}Z\r\n`)
+
+func TestEventICS(t *testing.T) {
+	ev := EventData{
+		Title:       "Standup",
+		Start:       "2026-11-03T09:30",
+		Timezone:    "Europe/Berlin",
+		RRule:       "FREQ=WEEKLY;BYDAY=TU;UNTIL=20270330T083000Z",
+		Description: strings.Repeat("é", 40),
+	}
+	b, err := ev.ICS("abc@qr-saas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dtstampRe.Match(b) {
+		t.Fatalf("no DTSTAMP in %q", b)
+	}
+	got := dtstampRe.ReplaceAllString(string(b), "")
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//qr-saas//events//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20250330T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"TZNAME:CEST",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20251026T030000",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"TZNAME:CET",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:abc@qr-saas",
+		"SUMMARY:Standup",
+		"DTSTART;TZID=Europe/Berlin:20261103T093000",
+		"DTEND;TZID=Europe/Berlin:20261103T103000",
+		"RRULE:FREQ=WEEKLY;BYDAY=TU;UNTIL=20270330T083000Z",
+		// 12 bytes of DESCRIPTION: and 31 two byte runes make 74 octets
+		"DESCRIPTION:" + strings.Repeat("é", 31),
+		" " + strings.Repeat("é", 9),
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestVTimezone(t *testing.T) {
+	tests := []struct {
+		tz   string
+		want []string // lines between TZID and END:VTIMEZONE
+	}{
+		{"Asia/Kolkata", []string{
+			"BEGIN:STANDARD", "DTSTART:19700101T000000", "TZOFFSETFROM:+0530", "TZOFFSETTO:+0530", "TZNAME:IST", "END:STANDARD",
+		}},
+		{"America/New_York", []string{
+			"BEGIN:DAYLIGHT", "DTSTART:20250309T020000", "TZOFFSETFROM:-0500", "TZOFFSETTO:-0400", "TZNAME:EDT",
+			"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU", "END:DAYLIGHT",
+			"BEGIN:STANDARD", "DTSTART:20251102T020000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500", "TZNAME:EST",
+			"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU", "END:STANDARD",
+		}},
+	}
+	for _, tt := range tests {
+		ev, err := EventData{Title: "x", Start: "2026-01-05T09:00", Timezone: tt.tz, RRule: "FREQ=DAILY"}.validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join(vtimezone(ev.tz, ev.loc, 2025), "\n")
+		want := strings.Join(append(append([]string{"BEGIN:VTIMEZONE", "TZID:" + tt.tz}, tt.want...), "END:VTIMEZONE"), "\n")
+		if got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.tz, got, want)
+		}
+	}
+}
+
+func TestEventInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data EventData
+	}{
+		{"no title", EventData{Start: "2026-11-05T09:00"}},
+		{"no start", EventData{Title: "x"}},
+		{"unknown timezone", EventData{Title: "x", Start: "2026-11-05T09:00", Timezone: "Mars/Olympus"}},
+		{"end before start", EventData{Title: "x", Start: "2026-11-05T09:00", End: "2026-11-05T08:00"}},
+		{"all day with a time", EventData{Title: "x", Start: "2026-11-05T09:00", AllDay: true}},
+		{"script url", EventData{Title: "x", Start: "2026-11-05T09:00", URL: "javascript:alert(1)"}},
+		{"unknown rrule part", EventData{Title: "x", Start: "2026-11-05T09:00", RRule: "FREQ=WEEKLY;BYHOUR=9"}},
+		{"rrule without freq", EventData{Title: "x", Start: "2026-11-05T09:00", RRule: "COUNT=3"}},
+		{"count and until", EventData{Title: "x", Start: "2026-11-05T09:00", RRule: "FREQ=DAILY;COUNT=3;UNTIL=20261201T000000Z"}},
+		{"local until", EventData{Title: "x", Start: "2026-11-05T09:00", Timezone: "Europe/Berlin", RRule: "FREQ=DAILY;UNTIL=20261201T000000"}},
+		{"date until of a timed event", EventData{Title: "x", Start: "2026-11-05T09:00", Timezone: "Europe/Berlin", RRule: "FREQ=DAILY;UNTIL=20261201"}},
+		{"time until of an all day event", EventData{Title: "x", Start: "2026-11-05", AllDay: true, RRule: "FREQ=DAILY;UNTIL=20261201T000000Z"}},
+	}
+	for _, tt := range tests {
+		if got, err := tt.data.Payload(); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("%s: got %q, %v; want ErrInvalidPayload", tt.name, got, err)
+		}
+	}
+}
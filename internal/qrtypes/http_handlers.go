@@ -1,11 +1,355 @@
 package qrtypes
 
 import (
+	"errors"
+	"net/http"
+
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qr/render"
+
 	"github.com/gin-gonic/gin"
 )
 
+type Handler struct {
+	svc Service
+}
+
 func RegisterRoutes(r *gin.RouterGroup, svc Service) {
-	r.POST("/:id/wifi", func(c *gin.Context) { /* ... */ })
-	r.POST("/:id/vcard", func(c *gin.Context) { /* ... */ })
-	r.GET("/:id", func(c *gin.Context) { /* ... */ })
+	h := &Handler{svc}
+
+	r.POST("/:id/wifi", h.CreateWiFi)
+	r.POST("/:id/vcard", h.CreateVCard)
+	r.POST("/:id/event", h.CreateEvent)
+	r.POST("/:id/upi", h.CreateUPI)
+	r.POST("/:id/epc", h.CreateEPC)
+	r.POST("/:id/crypto", h.CreateCrypto)
+	r.POST("/:id/sms", h.CreateSMS)
+	r.POST("/:id/email", h.CreateEmail)
+	r.POST("/:id/phone", h.CreatePhone)
+	r.POST("/:id/whatsapp", h.CreateWhatsApp)
+	r.POST("/:id/geo", h.CreateGeo)
+	r.POST("/:id/app", h.CreateAppLinks)
+	r.GET("/:id", h.GetQRTypeData)
+}
+
+// @Summary Make a code a WiFi code
+// @Description Validates the network and stores the escaped WIFI: payload as the code's content, phones offer to join the network when scanned.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body WiFiData true "network"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/wifi [post]
+func (h *Handler) CreateWiFi(c *gin.Context) {
+	var req WiFiData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateWiFi(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a contact card
+// @Description Encodes the contact as vCard 3.0, 4.0 or a compact MeCard. With dynamic the code redirects to a contact page (/t/{url_id}) with a .vcf download instead, and can be edited after printing.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body VCardRequest true "contact"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/vcard [post]
+func (h *Handler) CreateVCard(c *gin.Context) {
+	var req VCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateVCard(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req.VCardData, req.PageOptions)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a calendar event
+// @Description Encodes the event as an iCalendar VEVENT phones offer to add to the calendar. With dynamic the code redirects to an event page (/t/{url_id}) with an .ics download instead.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body EventRequest true "event"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/event [post]
+func (h *Handler) CreateEvent(c *gin.Context) {
+	var req EventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateEvent(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req.EventData, req.PageOptions)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a UPI payment
+// @Description Encodes a upi://pay link UPI apps open as a prefilled payment. The VPA, name, amount (INR, up to 100000) and note are validated.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body UPIData true "payment"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/upi [post]
+func (h *Handler) CreateUPI(c *gin.Context) {
+	var req UPIData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateUPI(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a SEPA transfer
+// @Description Encodes an EPC069-12 GiroCode European banking apps open as a prefilled credit transfer. The IBAN checksum and creditor reference are verified.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body EPCData true "transfer"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/epc [post]
+func (h *Handler) CreateEPC(c *gin.Context) {
+	var req EPCData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateEPC(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a crypto payment
+// @Description Encodes a BIP21 bitcoin: or EIP-681 ethereum: URI. Addresses are checksum verified (base58check, bech32/bech32m, EIP-55).
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body CryptoData true "payment"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/crypto [post]
+func (h *Handler) CreateCrypto(c *gin.Context) {
+	var req CryptoData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateCrypto(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a text message
+// @Description Encodes SMSTO:number:message (or an RFC 5724 sms: URI with format sms), phones open a prefilled message.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body SMSData true "message"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/sms [post]
+func (h *Handler) CreateSMS(c *gin.Context) {
+	var req SMSData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateSMS(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code an email
+// @Description Encodes a mailto: link with the subject and body filled in.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body EmailData true "email"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/email [post]
+func (h *Handler) CreateEmail(c *gin.Context) {
+	var req EmailData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateEmail(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a phone call
+// @Description Encodes a tel: link, phones offer to call the number.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body PhoneData true "phone"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/phone [post]
+func (h *Handler) CreatePhone(c *gin.Context) {
+	var req PhoneData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreatePhone(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a WhatsApp chat
+// @Description Encodes a wa.me click-to-chat link for an international number, with an optional prefilled message.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body WhatsAppData true "chat"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/whatsapp [post]
+func (h *Handler) CreateWhatsApp(c *gin.Context) {
+	var req WhatsAppData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateWhatsApp(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code a location
+// @Description Encodes a geo: URI, or with format maps an https map link for cameras that ignore geo:.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body GeoData true "location"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/geo [post]
+func (h *Handler) CreateGeo(c *gin.Context) {
+	var req GeoData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateGeo(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Make a code an app store link
+// @Description The code stays dynamic and redirects iPhones and iPads to the App Store, Android devices to Google Play and everything else to web_url.
+// @Tags QR Types
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body AppLinksData true "store links"
+// @Success 200 {object} qr.QRCode
+// @Router /api/qr/types/{id}/app [post]
+func (h *Handler) CreateAppLinks(c *gin.Context) {
+	var req AppLinksData
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	code, err := h.svc.CreateAppLinks(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Get the structured data of a typed code
+// @Tags QR Types
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "QR ID"
+// @Success 200 {object} QRTypeData
+// @Router /api/qr/types/{id} [get]
+func (h *Handler) GetQRTypeData(c *gin.Context) {
+	d, err := h.svc.GetQRTypeData(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// respondError writes the response for err and reports whether there was one
+func respondError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrQRNotFound), errors.Is(err, ErrNoTypeData):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRedirectSettings):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidPayload), errors.Is(err, render.ErrInvalidContent), errors.Is(err, qr.ErrLinearNeedsStatic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+	}
+	return true
 }
@@ -0,0 +1,170 @@
+package qrtypes
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+// SMS encodings
+const (
+	FormatSMSTO = "smsto" // SMSTO:number:message, what ZXing writes and every camera reads
+	FormatSMS   = "sms"   // RFC 5724 sms:number?body=message
+)
+
+// Geo encodings
+const (
+	FormatGeo  = "geo"  // geo: URI, opens the phone's map app
+	FormatMaps = "maps" // https map link, for cameras that ignore geo: (iOS)
+)
+
+// normalizePhone checks a phone number and strips its visual separators,
+// keeping a leading +
+func normalizePhone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !phoneRe.MatchString(s) {
+		return "", fmt.Errorf("%w: invalid phone number %q", ErrInvalidPayload, s)
+	}
+	var b strings.Builder
+	for i, r := range s {
+		if r >= '0' && r <= '9' || r == '+' && i == 0 {
+			b.WriteRune(r)
+		}
+	}
+	if n := strings.TrimPrefix(b.String(), "+"); len(n) < 3 {
+		return "", fmt.Errorf("%w: invalid phone number %q", ErrInvalidPayload, s)
+	}
+	return b.String(), nil
+}
+
+// Payload builds an SMSTO: or sms: payload, phones open the messaging
+// app with the message filled in
+func (m SMSData) Payload() (string, error) {
+	phone, err := normalizePhone(m.Phone)
+	if err != nil {
+		return "", err
+	}
+	message := strings.TrimSpace(m.Message)
+	switch strings.ToLower(strings.TrimSpace(m.Format)) {
+	case "", FormatSMSTO:
+		// The message is everything after the second colon, no escaping
+		return "SMSTO:" + phone + ":" + message, nil
+	case FormatSMS:
+		if message == "" {
+			return "sms:" + phone, nil
+		}
+		return "sms:" + phone + "?body=" + uriEscape(message), nil
+	}
+	return "", fmt.Errorf("%w: format must be smsto or sms", ErrInvalidPayload)
+}
+
+// Payload builds a mailto: link (RFC 6068) with the subject and body
+// filled in
+func (e EmailData) Payload() (string, error) {
+	to := strings.TrimSpace(e.To)
+	if a, err := mail.ParseAddress(to); err != nil || a.Address != to {
+		return "", fmt.Errorf("%w: invalid email %q", ErrInvalidPayload, e.To)
+	}
+	subject := strings.TrimSpace(e.Subject)
+	if strings.ContainsAny(subject, "\r\n") {
+		return "", fmt.Errorf("%w: subject can't contain line breaks", ErrInvalidPayload)
+	}
+
+	var params []string
+	if subject != "" {
+		params = append(params, "subject="+uriEscape(subject))
+	}
+	if body := strings.TrimSpace(e.Body); body != "" {
+		// Line breaks are CRLF in mail
+		body = strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+		params = append(params, "body="+uriEscape(body))
+	}
+	link := "mailto:" + mailtoAddress(to)
+	if len(params) > 0 {
+		link += "?" + strings.Join(params, "&")
+	}
+	return link, nil
+}
+
+// mailtoAddress percent-encodes an address for the to of a mailto: link
+// (RFC 6068): ?, &, # or % in the local part would otherwise start or
+// change its headers. Usual addresses stay readable, the domain's @ is
+// kept.
+func mailtoAddress(addr string) string {
+	at := strings.LastIndexByte(addr, '@')
+	var b strings.Builder
+	for i := 0; i < len(addr); i++ {
+		c := addr[i]
+		switch {
+		case i == at,
+			'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			strings.IndexByte("-._~!$'()*+;:", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// Payload builds a tel: link, scanning offers to call the number
+func (p PhoneData) Payload() (string, error) {
+	phone, err := normalizePhone(p.Phone)
+	if err != nil {
+		return "", err
+	}
+	return "tel:" + phone, nil
+}
+
+// Payload builds a WhatsApp click-to-chat link, which opens a chat with
+// the number (the app, or WhatsApp Web without it)
+func (w WhatsAppData) Payload() (string, error) {
+	phone, err := normalizePhone(w.Phone)
+	if err != nil {
+		return "", err
+	}
+	// wa.me takes the full international number in digits only: no +, no
+	// leading zeros or trunk prefix, 8 to 15 digits (E.164)
+	digits := strings.TrimPrefix(phone, "+")
+	if strings.HasPrefix(digits, "0") || len(digits) < 8 || len(digits) > 15 {
+		return "", fmt.Errorf("%w: WhatsApp needs the international number with country code, e.g. +919876543210", ErrInvalidPayload)
+	}
+	link := "https://wa.me/" + digits
+	if message := strings.TrimSpace(w.Message); message != "" {
+		link += "?text=" + uriEscape(message)
+	}
+	return link, nil
+}
+
+// Payload builds a geo: URI (RFC 5870) or, as a fallback for cameras that
+// don't handle geo:, a map link that opens in any browser
+func (g GeoData) Payload() (string, error) {
+	if g.Latitude == nil || g.Longitude == nil {
+		return "", fmt.Errorf("%w: latitude and longitude are required", ErrInvalidPayload)
+	}
+	lat, lon := *g.Latitude, *g.Longitude
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return "", fmt.Errorf("%w: latitude must be within -90 and 90, longitude within -180 and 180", ErrInvalidPayload)
+	}
+	// 6 decimals is about 10 cm, more only grows the code
+	coords := coordinate(lat) + "," + coordinate(lon)
+	label := strings.TrimSpace(g.Label)
+
+	switch strings.ToLower(strings.TrimSpace(g.Format)) {
+	case "", FormatGeo:
+		if label == "" {
+			return "geo:" + coords, nil
+		}
+		// The q=lat,lon(label) form is Android's, others ignore the query
+		return "geo:" + coords + "?q=" + coords + "(" + uriEscape(label) + ")", nil
+	case FormatMaps:
+		return "https://www.google.com/maps/search/?api=1&query=" + coords, nil
+	}
+	return "", fmt.Errorf("%w: format must be geo or maps", ErrInvalidPayload)
+}
+
+func coordinate(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
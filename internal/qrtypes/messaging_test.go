@@ -0,0 +1,101 @@
+package qrtypes
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMessagingPayloads(t *testing.T) {
+	lat, lon, tooFar := 48.8583701, 2.2944813, 90.5
+	tests := []struct {
+		name string
+		data interface{ Payload() (string, error) }
+		want string // "" when the data is invalid
+	}{
+		{
+			name: "smsto keeps the message as is",
+			data: SMSData{Phone: "+1 (555) 010-0100", Message: "Meet at 10:30; ok? 100%"},
+			want: "SMSTO:+15550100100:Meet at 10:30; ok? 100%",
+		},
+		{
+			name: "sms body",
+			data: SMSData{Phone: "555 0100", Message: "Meet at 10:30 & bring #5\n+1", Format: "sms"},
+			want: "sms:5550100?body=Meet%20at%2010%3A30%20%26%20bring%20%235%0A%2B1",
+		},
+		{
+			name: "sms without a message",
+			data: SMSData{Phone: "5550100", Format: "SMS"},
+			want: "sms:5550100",
+		},
+		{
+			name: "sms bad phone",
+			data: SMSData{Phone: "call me"},
+		},
+		{
+			name: "sms bad format",
+			data: SMSData{Phone: "5550100", Format: "mms"},
+		},
+		{
+			name: "mailto",
+			data: EmailData{To: "jane.doe+news@example.com", Subject: "Hi & welcome", Body: "Line 1\nLine 2"},
+			want: "mailto:jane.doe+news@example.com?subject=Hi%20%26%20welcome&body=Line%201%0D%0ALine%202",
+		},
+		{
+			name: "mailto escapes the local part",
+			data: EmailData{To: "a?cc=evil&b#c%d@example.com"},
+			want: "mailto:a%3Fcc%3Devil%26b%23c%25d@example.com",
+		},
+		{
+			name: "mailto with a display name",
+			data: EmailData{To: "Jane <jane@example.com>"},
+		},
+		{
+			name: "mailto subject with a line break",
+			data: EmailData{To: "jane@example.com", Subject: "Hi\r\nBcc: evil@example.com"},
+		},
+		{
+			name: "tel",
+			data: PhoneData{Phone: "+44 20 7946 0958"},
+			want: "tel:+442079460958",
+		},
+		{
+			name: "whatsapp",
+			data: WhatsAppData{Phone: "+91 98765 43210", Message: "Hi, is this available?"},
+			want: "https://wa.me/919876543210?text=Hi%2C%20is%20this%20available%3F",
+		},
+		{
+			name: "whatsapp without country code",
+			data: WhatsAppData{Phone: "098765 43210"},
+		},
+		{
+			name: "geo with a label",
+			data: GeoData{Latitude: &lat, Longitude: &lon, Label: "Tour Eiffel (top)"},
+			want: "geo:48.85837,2.294481?q=48.85837,2.294481(Tour%20Eiffel%20%28top%29)",
+		},
+		{
+			name: "maps link",
+			data: GeoData{Latitude: &lat, Longitude: &lon, Format: "maps"},
+			want: "https://www.google.com/maps/search/?api=1&query=48.85837,2.294481",
+		},
+		{
+			name: "geo out of range",
+			data: GeoData{Latitude: &tooFar, Longitude: &lon},
+		},
+		{
+			name: "geo without longitude",
+			data: GeoData{Latitude: &lat},
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.data.Payload()
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("%s: got %q, %v; want ErrInvalidPayload", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v\nwant %q", tt.name, got, err, tt.want)
+		}
+	}
+}
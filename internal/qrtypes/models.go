@@ -1,20 +1,204 @@
 package qrtypes
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// Type names, stored as the code's qr_type
+const (
+	TypeWiFi     = "wifi"
+	TypeVCard    = "vcard"
+	TypeEvent    = "event"
+	TypeUPI      = "upi"
+	TypeEPC      = "epc"
+	TypeCrypto   = "crypto"
+	TypeSMS      = "sms"
+	TypeEmail    = "email"
+	TypePhone    = "phone"
+	TypeWhatsApp = "whatsapp"
+	TypeGeo      = "geo"
+	TypeApp      = "app" // dynamic, see AppLinksData
+)
+
+// QRTypeData is the structured data behind a typed code; the encoded
+// payload of static codes is kept on the code itself as its target_url.
 type QRTypeData struct {
-	Type     string                 `json:"type"`
-	Metadata map[string]interface{} `json:"metadata"`
+	Type      string                 `json:"type"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	UpdatedAt time.Time              `json:"updated_at"`
 }
 
+// Decode reads the stored data into v, one of the *Data types
+func (d *QRTypeData) Decode(v interface{}) error {
+	raw, err := json.Marshal(d.Metadata)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// WiFi security types
+const (
+	SecurityWPA    = "WPA"
+	SecurityWPA2   = "WPA2"
+	SecurityWPA3   = "WPA3"
+	SecurityWEP    = "WEP"
+	SecurityNoPass = "nopass"
+)
+
 type WiFiData struct {
 	SSID     string `json:"ssid"`
 	Password string `json:"password"`
-	Security string `json:"security"` // WPA/WEP
+	Security string `json:"security"` // WPA, WPA2, WPA3, WEP or nopass; defaults to WPA with a password
+	Hidden   bool   `json:"hidden"`   // network doesn't broadcast its SSID
 }
 
+// PageOptions publish a code's data on a landing page (/t/:url_id) the
+// code redirects to, so it can be edited after printing, instead of
+// encoding it in the code.
+type PageOptions struct {
+	Dynamic    bool   `json:"dynamic"`
+	TemplateID string `json:"template_id"` // a template of the type's category, defaults to the first global one
+}
+
+// vCard encodings
+const (
+	FormatVCard3 = "vcard3"
+	FormatVCard4 = "vcard4"
+	FormatMeCard = "mecard" // compact, for small codes; no photo, title or socials
+)
+
 type VCardData struct {
-	FullName string `json:"full_name"`
-	Phone    string `json:"phone"`
-	Email    string `json:"email"`
-	Company  string `json:"company"`
-	Website  string `json:"website"`
+	FullName  string          `json:"full_name"`
+	FirstName string          `json:"first_name"` // split of the name, guessed from full_name when empty
+	LastName  string          `json:"last_name"`
+	Company   string          `json:"company"`
+	Title     string          `json:"title"`
+	Phone     string          `json:"phone"` // single phone of older clients, same as one "cell" in phones
+	Email     string          `json:"email"`
+	Website   string          `json:"website"`
+	Phones    []TypedValue    `json:"phones"` // types: cell, work, home, fax, other
+	Emails    []TypedValue    `json:"emails"` // types: work, home, other
+	Address   *Address        `json:"address"`
+	Note      string          `json:"note"`
+	PhotoURL  string          `json:"photo_url"`
+	Socials   []SocialProfile `json:"socials"`
+	Format    string          `json:"format"` // vcard3 (default), vcard4 or mecard
+}
+
+type TypedValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type Address struct {
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Type       string `json:"type"` // work (default), home or other
+}
+
+type SocialProfile struct {
+	Network string `json:"network"` // e.g. linkedin, x, instagram
+	URL     string `json:"url"`
+}
+
+// VCardRequest is the body of POST /:id/vcard
+type VCardRequest struct {
+	VCardData
+	PageOptions
+}
+
+type EventData struct {
+	Title       string `json:"title"`
+	Start       string `json:"start"`    // 2006-01-02T15:04 in timezone (or with an offset), a date for all day events
+	End         string `json:"end"`      // defaults to an hour after start, the same day for all day events
+	Timezone    string `json:"timezone"` // IANA name, e.g. Asia/Kolkata; defaults to UTC
+	AllDay      bool   `json:"all_day"`
+	Location    string `json:"location"`
+	Description string `json:"description"`
+	URL         string `json:"url"`   // e.g. the webinar link
+	RRule       string `json:"rrule"` // recurrence, e.g. FREQ=WEEKLY;BYDAY=TU;COUNT=6
+}
+
+// EventRequest is the body of POST /:id/event
+type EventRequest struct {
+	EventData
+	PageOptions
+}
+
+// UPIData is an Indian UPI payment request
+type UPIData struct {
+	VPA      string `json:"vpa"`      // payee address, e.g. shop@okaxis
+	Name     string `json:"name"`     // payee name shown in the app
+	Amount   string `json:"amount"`   // INR as a decimal string, e.g. "499.00"; empty lets the payer enter it
+	Currency string `json:"currency"` // INR only
+	Note     string `json:"note"`     // transaction note
+}
+
+// EPCData is a SEPA credit transfer (EPC GiroCode), amounts in EUR
+type EPCData struct {
+	Name        string `json:"name"` // beneficiary
+	IBAN        string `json:"iban"`
+	BIC         string `json:"bic"`         // optional within the EEA
+	Amount      string `json:"amount"`      // e.g. "12.50"
+	Purpose     string `json:"purpose"`     // ISO 20022 purpose code, e.g. GDDS
+	Reference   string `json:"reference"`   // ISO 11649 creditor reference (RF...), or
+	Text        string `json:"text"`        // an unstructured remittance text
+	Information string `json:"information"` // note to the payer, not transferred
+}
+
+// Crypto networks
+const (
+	NetworkBitcoin  = "bitcoin"
+	NetworkEthereum = "ethereum"
+)
+
+type CryptoData struct {
+	Network string `json:"network"` // bitcoin or ethereum
+	Address string `json:"address"`
+	Amount  string `json:"amount"` // in BTC or ETH, e.g. "0.015"
+	Label   string `json:"label"`  // bitcoin only
+	Message string `json:"message"`
+	ChainID int64  `json:"chain_id"` // ethereum only, defaults to mainnet (1)
+}
+
+type SMSData struct {
+	Phone   string `json:"phone"`
+	Message string `json:"message"`
+	Format  string `json:"format"` // smsto (default) or sms
+}
+
+type EmailData struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type PhoneData struct {
+	Phone string `json:"phone"`
+}
+
+type WhatsAppData struct {
+	Phone   string `json:"phone"`   // international, e.g. +91 98765 43210
+	Message string `json:"message"` // prefilled chat message
+}
+
+type GeoData struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Label     string   `json:"label"`  // place name, shown by Android maps
+	Format    string   `json:"format"` // geo (default) or maps, an https map link for cameras without geo: support
+}
+
+// AppLinksData sends one code to the app's store page on the scanning
+// device: the App Store on iOS, Google Play on Android and the web URL
+// anywhere else.
+type AppLinksData struct {
+	IOSURL     string `json:"ios_url"`     // e.g. https://apps.apple.com/app/id123456789
+	AndroidURL string `json:"android_url"` // e.g. https://play.google.com/store/apps/details?id=com.example
+	WebURL     string `json:"web_url"`     // desktops and other devices, becomes the code's target_url; defaults to the first store link
 }
@@ -0,0 +1,111 @@
+package qrtypes
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Payloads are what a static code carries; phones act on them without a
+// network round trip (add contact, join network, ...).
+
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// Payload builds the WIFI: string phone cameras offer to join, e.g.
+// WIFI:T:WPA;S:Cafe;P:secret;; (the ZXing format, there is no formal spec)
+func (w WiFiData) Payload() (string, error) {
+	security, err := w.security()
+	if err != nil {
+		return "", err
+	}
+	if w.SSID == "" || len(w.SSID) > 32 {
+		return "", fmt.Errorf("%w: ssid must be 1 to 32 bytes", ErrInvalidPayload)
+	}
+
+	switch security {
+	case SecurityNoPass:
+		if w.Password != "" {
+			return "", fmt.Errorf("%w: an open network has no password", ErrInvalidPayload)
+		}
+	case SecurityWEP:
+		// 40 or 104 bit keys, as ASCII or hex
+		if n := len(w.Password); !(n == 5 || n == 13 || (isHex(w.Password) && (n == 10 || n == 26))) {
+			return "", fmt.Errorf("%w: WEP keys are 5 or 13 characters, or 10 or 26 hex digits", ErrInvalidPayload)
+		}
+	default:
+		// A passphrase, or the raw 256 bit PSK in hex
+		if n := len(w.Password); !(n >= 8 && n <= 63 || n == 64 && isHex(w.Password)) {
+			return "", fmt.Errorf("%w: WPA passwords are 8 to 63 characters", ErrInvalidPayload)
+		}
+	}
+
+	// WPA2 and WPA3 networks are joined as WPA, that's what phones read;
+	// they negotiate the actual protocol with the access point.
+	t := security
+	if security == SecurityWPA2 || security == SecurityWPA3 {
+		t = SecurityWPA
+	}
+
+	var b strings.Builder
+	b.WriteString("WIFI:T:" + t + ";S:" + wifiField(w.SSID) + ";")
+	if security != SecurityNoPass {
+		b.WriteString("P:" + wifiField(w.Password) + ";")
+	}
+	if w.Hidden {
+		b.WriteString("H:true;")
+	}
+	b.WriteString(";")
+	return b.String(), nil
+}
+
+// security normalizes the security type, defaulting on the password
+func (w WiFiData) security() (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(w.Security)) {
+	case "":
+		if w.Password == "" {
+			return SecurityNoPass, nil
+		}
+		return SecurityWPA, nil
+	case "WPA":
+		return SecurityWPA, nil
+	case "WPA2":
+		return SecurityWPA2, nil
+	case "WPA3":
+		return SecurityWPA3, nil
+	case "WEP":
+		return SecurityWEP, nil
+	case "NOPASS", "NONE", "OPEN":
+		return SecurityNoPass, nil
+	}
+	return "", fmt.Errorf("%w: security must be WPA, WPA2, WPA3, WEP or nopass", ErrInvalidPayload)
+}
+
+var wifiEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, ":", `\:`, `"`, `\"`)
+
+// wifiField escapes the characters that delimit fields. Hex looking
+// values are not quoted as ZXing suggests, not every reader strips the
+// quotes again.
+func wifiField(s string) string {
+	return wifiEscaper.Replace(s)
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// uriEscape percent-encodes a URI query value, spaces as %20: a + is only
+// a space in forms, many apps (UPI, mail, SMS) show it literally
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,68 @@
+package qrtypes
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWiFiPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		data WiFiData
+		want string // "" when the data is invalid
+	}{
+		{
+			name: "wpa",
+			data: WiFiData{SSID: "Cafe", Password: "secret123"},
+			want: "WIFI:T:WPA;S:Cafe;P:secret123;;",
+		},
+		{
+			name: "wpa3 joins as wpa",
+			data: WiFiData{SSID: "Cafe", Password: "secret123", Security: "wpa3", Hidden: true},
+			want: "WIFI:T:WPA;S:Cafe;P:secret123;H:true;;",
+		},
+		{
+			name: "open",
+			data: WiFiData{SSID: "Guest", Security: "open"},
+			want: "WIFI:T:nopass;S:Guest;;",
+		},
+		{
+			name: "escaped delimiters",
+			data: WiFiData{SSID: `My;Net,"5G":x`, Password: `p\a;s,s:w"d`},
+			want: `WIFI:T:WPA;S:My\;Net\,\"5G\"\:x;P:p\\a\;s\,s\:w\"d;;`,
+		},
+		{
+			name: "hex wep key",
+			data: WiFiData{SSID: "Old", Password: "0123456789", Security: "WEP"},
+			want: "WIFI:T:WEP;S:Old;P:0123456789;;",
+		},
+		{
+			name: "short wpa password",
+			data: WiFiData{SSID: "Cafe", Password: "short"},
+		},
+		{
+			name: "open with a password",
+			data: WiFiData{SSID: "Cafe", Password: "secret123", Security: "nopass"},
+		},
+		{
+			name: "long ssid",
+			data: WiFiData{SSID: "0123456789012345678901234567890123"},
+		},
+		{
+			name: "bad wep key",
+			data: WiFiData{SSID: "Old", Password: "012345", Security: "WEP"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.data.Payload()
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("%s: got %q, %v; want ErrInvalidPayload", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v\nwant %q", tt.name, got, err, tt.want)
+		}
+	}
+}
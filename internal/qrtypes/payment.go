@@ -0,0 +1,420 @@
+package qrtypes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Payment codes move money, so every field is checked strictly: a typo in
+// an IBAN or address must fail here rather than at the payer's bank.
+
+// Limits of the payment formats
+const (
+	maxUPIAmount    = "100000"       // INR, the standard UPI per-transaction limit
+	maxEPCAmount    = "999999999.99" // EUR, EPC069-12
+	maxBTCAmount    = "21000000"
+	maxETHAmount    = "1000000"
+	maxEPCPayload   = 331 // bytes, EPC069-12
+	epcNameMax      = 70
+	epcReferenceMax = 35
+	epcTextMax      = 140
+	upiNameMax      = 99
+	upiNoteMax      = 80
+)
+
+var (
+	vpaRe     = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{1,255}@[a-zA-Z][a-zA-Z0-9]{1,63}$`)
+	bicRe     = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	purposeRe = regexp.MustCompile(`^[A-Z]{4}$`)
+	ethAddrRe = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// Payload builds a UPI deep link, e.g.
+// upi://pay?pa=shop@okaxis&pn=Shop&am=499.00&cu=INR&tn=Order%2042
+func (u UPIData) Payload() (string, error) {
+	vpa := strings.TrimSpace(u.VPA)
+	if !vpaRe.MatchString(vpa) {
+		return "", fmt.Errorf("%w: vpa must look like name@bank", ErrInvalidPayload)
+	}
+	name := strings.TrimSpace(u.Name)
+	if name == "" || utf8.RuneCountInString(name) > upiNameMax {
+		return "", fmt.Errorf("%w: name is required, at most %d characters", ErrInvalidPayload, upiNameMax)
+	}
+	if c := strings.ToUpper(strings.TrimSpace(u.Currency)); c != "" && c != "INR" {
+		return "", fmt.Errorf("%w: UPI payments are in INR", ErrInvalidPayload)
+	}
+	note := strings.TrimSpace(u.Note)
+	if utf8.RuneCountInString(note) > upiNoteMax {
+		return "", fmt.Errorf("%w: note is at most %d characters", ErrInvalidPayload, upiNoteMax)
+	}
+
+	// pa is left unescaped, some UPI apps don't decode %40
+	link := "upi://pay?pa=" + vpa + "&pn=" + uriEscape(name)
+	if u.Amount != "" {
+		amount, err := parseAmount(u.Amount, 2, maxUPIAmount)
+		if err != nil {
+			return "", err
+		}
+		link += "&am=" + amount.FloatString(2)
+	}
+	link += "&cu=INR"
+	if note != "" {
+		link += "&tn=" + uriEscape(note)
+	}
+	return link, nil
+}
+
+// Payload builds an EPC069-12 SEPA credit transfer (GiroCode), which
+// European banking apps turn into a prefilled transfer. Version 002 with
+// UTF-8, so the BIC is optional.
+func (e EPCData) Payload() (string, error) {
+	name := strings.TrimSpace(e.Name)
+	if name == "" || utf8.RuneCountInString(name) > epcNameMax {
+		return "", fmt.Errorf("%w: name is required, at most %d characters", ErrInvalidPayload, epcNameMax)
+	}
+	iban, err := normalizeIBAN(e.IBAN)
+	if err != nil {
+		return "", err
+	}
+	bic := strings.ToUpper(strings.ReplaceAll(e.BIC, " ", ""))
+	if bic != "" && !bicRe.MatchString(bic) {
+		return "", fmt.Errorf("%w: invalid BIC %q", ErrInvalidPayload, e.BIC)
+	}
+
+	amount := ""
+	if e.Amount != "" {
+		a, err := parseAmount(e.Amount, 2, maxEPCAmount)
+		if err != nil {
+			return "", err
+		}
+		amount = "EUR" + a.FloatString(2)
+	}
+
+	purpose := strings.ToUpper(strings.TrimSpace(e.Purpose))
+	if purpose != "" && !purposeRe.MatchString(purpose) {
+		return "", fmt.Errorf("%w: purpose is a 4 letter ISO 20022 code, e.g. GDDS", ErrInvalidPayload)
+	}
+
+	reference := strings.ToUpper(strings.ReplaceAll(e.Reference, " ", ""))
+	text := strings.TrimSpace(e.Text)
+	switch {
+	case reference != "" && text != "":
+		return "", fmt.Errorf("%w: use a reference or a text, not both", ErrInvalidPayload)
+	case reference != "" && (len(reference) > epcReferenceMax || !validCreditorReference(reference)):
+		return "", fmt.Errorf("%w: reference must be an ISO 11649 creditor reference (RF...)", ErrInvalidPayload)
+	case utf8.RuneCountInString(text) > epcTextMax:
+		return "", fmt.Errorf("%w: text is at most %d characters", ErrInvalidPayload, epcTextMax)
+	}
+
+	info := strings.TrimSpace(e.Information)
+	if utf8.RuneCountInString(info) > epcNameMax {
+		return "", fmt.Errorf("%w: information is at most %d characters", ErrInvalidPayload, epcNameMax)
+	}
+	for _, field := range []string{name, text, info} {
+		if strings.ContainsAny(field, "\r\n") {
+			return "", fmt.Errorf("%w: fields can't contain line breaks", ErrInvalidPayload)
+		}
+	}
+
+	lines := []string{"BCD", "002", "1", "SCT", bic, name, iban, amount, purpose, reference, text, info}
+	// Trailing empty fields may be left out
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	payload := strings.Join(lines, "\n")
+	if len(payload) > maxEPCPayload {
+		return "", fmt.Errorf("%w: EPC payload exceeds %d bytes", ErrInvalidPayload, maxEPCPayload)
+	}
+	return payload, nil
+}
+
+// ibanLengths of the SEPA countries
+var ibanLengths = map[string]int{
+	"AD": 24, "AT": 20, "BE": 16, "BG": 22, "CH": 21, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22, "GI": 23, "GR": 27,
+	"HR": 21, "HU": 28, "IE": 22, "IS": 26, "IT": 27, "LI": 21, "LT": 20, "LU": 20,
+	"LV": 21, "MC": 27, "MT": 31, "NL": 18, "NO": 15, "PL": 28, "PT": 25, "RO": 24,
+	"SE": 24, "SI": 19, "SK": 24, "SM": 27, "VA": 22,
+}
+
+// normalizeIBAN strips spaces, checks the country length and the ISO 7064
+// mod 97 checksum
+func normalizeIBAN(s string) (string, error) {
+	iban := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if len(iban) < 4 {
+		return "", fmt.Errorf("%w: iban is required", ErrInvalidPayload)
+	}
+	if n, ok := ibanLengths[iban[:2]]; !ok || len(iban) != n {
+		return "", fmt.Errorf("%w: %q is not a SEPA IBAN of the right length", ErrInvalidPayload, s)
+	}
+	if !mod97(iban[4:] + iban[:4]) {
+		return "", fmt.Errorf("%w: IBAN checksum doesn't match, check for typos", ErrInvalidPayload)
+	}
+	return iban, nil
+}
+
+// validCreditorReference checks an ISO 11649 RF reference
+func validCreditorReference(ref string) bool {
+	if len(ref) < 5 || !strings.HasPrefix(ref, "RF") {
+		return false
+	}
+	return mod97(ref[4:] + ref[:4])
+}
+
+// mod97 is the ISO 7064 MOD 97-10 check: s, letters counted as 10-35,
+// must leave 1 modulo 97
+func mod97(s string) bool {
+	rem := 0
+	for _, r := range s {
+		var v int
+		switch {
+		case r >= '0' && r <= '9':
+			v = int(r - '0')
+		case r >= 'A' && r <= 'Z':
+			v = int(r-'A') + 10
+		default:
+			return false
+		}
+		if v >= 10 {
+			rem = (rem*100 + v) % 97
+		} else {
+			rem = (rem*10 + v) % 97
+		}
+	}
+	return rem == 1
+}
+
+// Payload builds a BIP21 bitcoin: or EIP-681 ethereum: URI, which wallet
+// apps open as a prefilled payment
+func (c CryptoData) Payload() (string, error) {
+	switch strings.ToLower(strings.TrimSpace(c.Network)) {
+	case NetworkBitcoin:
+		return c.bip21()
+	case NetworkEthereum:
+		return c.eip681()
+	}
+	return "", fmt.Errorf("%w: network must be bitcoin or ethereum", ErrInvalidPayload)
+}
+
+func (c CryptoData) bip21() (string, error) {
+	address := strings.TrimSpace(c.Address)
+	if !validBitcoinAddress(address) {
+		return "", fmt.Errorf("%w: invalid bitcoin address, check for typos", ErrInvalidPayload)
+	}
+	q := url.Values{}
+	if c.Amount != "" {
+		amount, err := parseAmount(c.Amount, 8, maxBTCAmount)
+		if err != nil {
+			return "", err
+		}
+		q.Set("amount", strings.TrimRight(strings.TrimRight(amount.FloatString(8), "0"), "."))
+	}
+	if l := strings.TrimSpace(c.Label); l != "" {
+		q.Set("label", l)
+	}
+	if m := strings.TrimSpace(c.Message); m != "" {
+		q.Set("message", m)
+	}
+	uri := "bitcoin:" + address
+	if len(q) > 0 {
+		uri += "?" + strings.ReplaceAll(q.Encode(), "+", "%20") // see uriEscape
+	}
+	return uri, nil
+}
+
+func (c CryptoData) eip681() (string, error) {
+	address := strings.TrimSpace(c.Address)
+	if !validEthereumAddress(address) {
+		return "", fmt.Errorf("%w: invalid ethereum address, check for typos", ErrInvalidPayload)
+	}
+	if c.ChainID < 0 {
+		return "", fmt.Errorf("%w: invalid chain_id", ErrInvalidPayload)
+	}
+	if c.Label != "" || c.Message != "" {
+		return "", fmt.Errorf("%w: label and message are bitcoin only", ErrInvalidPayload)
+	}
+	uri := "ethereum:" + address
+	if c.ChainID > 1 {
+		uri += "@" + strconv.FormatInt(c.ChainID, 10)
+	}
+	if c.Amount != "" {
+		amount, err := parseAmount(c.Amount, 18, maxETHAmount)
+		if err != nil {
+			return "", err
+		}
+		// value is in wei
+		wei := new(big.Rat).Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)))
+		uri += "?value=" + wei.Num().String()
+	}
+	return uri, nil
+}
+
+// parseAmount reads a positive decimal with at most decimals places, up
+// to max. Exact, no floats.
+func parseAmount(s string, decimals int, max string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || !allDigits(whole) || (frac != "" && !allDigits(frac)) || strings.HasSuffix(s, ".") {
+		return nil, fmt.Errorf("%w: amount must be a number like 12.50", ErrInvalidPayload)
+	}
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("%w: amount has more than %d decimals", ErrInvalidPayload, decimals)
+	}
+	amount, _ := new(big.Rat).SetString(s)
+	limit, _ := new(big.Rat).SetString(max)
+	if amount.Sign() <= 0 || amount.Cmp(limit) > 0 {
+		return nil, fmt.Errorf("%w: amount must be above 0 and at most %s", ErrInvalidPayload, max)
+	}
+	return amount, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validBitcoinAddress checks a mainnet address: base58check P2PKH (1...)
+// and P2SH (3...), or bech32/bech32m segwit (bc1...)
+func validBitcoinAddress(s string) bool {
+	if strings.HasPrefix(strings.ToLower(s), "bc1") {
+		return validSegwit(s)
+	}
+	payload, ok := base58Check(s)
+	return ok && len(payload) == 21 && (payload[0] == 0x00 || payload[0] == 0x05)
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Check decodes s and verifies its 4 byte double SHA-256 checksum
+func base58Check(s string) ([]byte, bool) {
+	if len(s) < 26 || len(s) > 35 {
+		return nil, false
+	}
+	n := new(big.Int)
+	for _, r := range s {
+		i := strings.IndexRune(base58Alphabet, r)
+		if i < 0 {
+			return nil, false
+		}
+		n.Mul(n, big.NewInt(58))
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	decoded := n.Bytes()
+	for _, r := range s {
+		if r != '1' {
+			break
+		}
+		decoded = append([]byte{0}, decoded...)
+	}
+	if len(decoded) < 5 {
+		return nil, false
+	}
+	payload, sum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return payload, string(second[:4]) == string(sum)
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// validSegwit checks a bc1 address: bech32 for witness v0, bech32m for
+// v1+ (BIP173, BIP350), with a valid program length
+func validSegwit(s string) bool {
+	if len(s) < 14 || len(s) > 90 || (strings.ToLower(s) != s && strings.ToUpper(s) != s) {
+		return false
+	}
+	s = strings.ToLower(s)
+	data := make([]byte, 0, len(s)-3)
+	for _, r := range s[3:] {
+		i := strings.IndexRune(bech32Charset, r)
+		if i < 0 {
+			return false
+		}
+		data = append(data, byte(i))
+	}
+	if len(data) < 7 {
+		return false
+	}
+
+	// polymod over the expanded hrp "bc" and the data
+	values := []byte{3, 3, 0, 2, 3}
+	values = append(values, data...)
+	chk := uint32(1)
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := range gen {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+
+	version := data[0]
+	switch {
+	case version == 0 && chk != 1:
+		return false
+	case version > 0 && chk != 0x2bc830a3:
+		return false
+	case version > 16:
+		return false
+	}
+
+	// Regroup the 5 bit program (without version and checksum) into bytes
+	var acc, bits uint
+	var program []byte
+	for _, v := range data[1 : len(data)-6] {
+		acc = acc<<5 | uint(v)
+		bits += 5
+		for bits >= 8 {
+			bits -= 8
+			program = append(program, byte(acc>>bits))
+		}
+	}
+	if bits >= 5 || acc&(1<<bits-1) != 0 {
+		return false
+	}
+	if version == 0 {
+		return len(program) == 20 || len(program) == 32
+	}
+	return len(program) >= 2 && len(program) <= 40
+}
+
+// validEthereumAddress checks the format and, for mixed case addresses,
+// the EIP-55 checksum
+func validEthereumAddress(s string) bool {
+	if !ethAddrRe.MatchString(s) {
+		return false
+	}
+	hexPart := s[2:]
+	if strings.ToLower(hexPart) == hexPart || strings.ToUpper(hexPart) == hexPart {
+		return true
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(strings.ToLower(hexPart)))
+	hash := hex.EncodeToString(h.Sum(nil))
+	for i, r := range hexPart {
+		if r >= '0' && r <= '9' {
+			continue
+		}
+		upper := hash[i] >= '8'
+		if upper != (r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
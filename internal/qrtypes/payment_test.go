@@ -0,0 +1,204 @@
+package qrtypes
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeIBAN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" when invalid
+	}{
+		{"GB82WEST12345698765432", "GB82WEST12345698765432"},
+		{"de89 3704 0044 0532 0130 00", "DE89370400440532013000"},
+		{"NL91ABNA0417164300", "NL91ABNA0417164300"},
+		{"DE89370400440532013001", ""}, // checksum
+		{"DE98370400440532013000", ""}, // swapped check digits
+		{"GB82WEST1234569876543", ""},  // too short
+		{"US64SVBKUS6S3300958879", ""}, // not SEPA
+		{"DE89-3704-0044-0532-0130", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := normalizeIBAN(tt.in)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("normalizeIBAN(%q) = %q, %v; want ErrInvalidPayload", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeIBAN(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidBitcoinAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", true},                                          // P2PKH
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", true},                                          // P2SH
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", true},                                  // BIP173 v0
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", true},                                  // upper case
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", true},              // BIP350 v1
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", false},                                         // checksum
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfN0", false},                                         // 0 isn't base58
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", false},                                         // testnet
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", false},                                 // checksum
+		{"bc1Qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", false},                                 // mixed case
+		{"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", false},                                 // testnet
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7k7grplx", false}, // v1 with a bech32 checksum
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validBitcoinAddress(tt.addr); got != tt.want {
+			t.Errorf("validBitcoinAddress(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestValidEthereumAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true}, // EIP-55
+		{"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", true},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true}, // no checksum
+		{"0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", true},
+		{"0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false}, // checksum case
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe", false},  // too short
+		{"5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+		{"0xZaAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+	}
+	for _, tt := range tests {
+		if got := validEthereumAddress(tt.addr); got != tt.want {
+			t.Errorf("validEthereumAddress(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestPaymentPayloads(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{ Payload() (string, error) }
+		want string // "" when the data is invalid
+	}{
+		{
+			name: "upi",
+			data: UPIData{VPA: "shop@okaxis", Name: "Corner Shop", Amount: "499", Note: "Order #42"},
+			want: "upi://pay?pa=shop@okaxis&pn=Corner%20Shop&am=499.00&cu=INR&tn=Order%20%2342",
+		},
+		{
+			name: "upi without amount",
+			data: UPIData{VPA: "shop@okaxis", Name: "Shop"},
+			want: "upi://pay?pa=shop@okaxis&pn=Shop&cu=INR",
+		},
+		{
+			name: "upi over the limit",
+			data: UPIData{VPA: "shop@okaxis", Name: "Shop", Amount: "100000.01"},
+		},
+		{
+			name: "upi in dollars",
+			data: UPIData{VPA: "shop@okaxis", Name: "Shop", Currency: "USD"},
+		},
+		{
+			name: "upi bad vpa",
+			data: UPIData{VPA: "shop", Name: "Shop"},
+		},
+		{
+			name: "epc with text",
+			data: EPCData{Name: "Red Cross", IBAN: "DE89 3704 0044 0532 0130 00", BIC: "cobadeffxxx", Amount: "12.5", Text: "Donation"},
+			want: "BCD\n002\n1\nSCT\nCOBADEFFXXX\nRed Cross\nDE89370400440532013000\nEUR12.50\n\n\nDonation",
+		},
+		{
+			name: "epc with reference",
+			data: EPCData{Name: "ACME", IBAN: "GB82WEST12345698765432", Purpose: "gdds", Reference: "RF18 5390 0754 7034"},
+			want: "BCD\n002\n1\nSCT\n\nACME\nGB82WEST12345698765432\n\nGDDS\nRF18539007547034",
+		},
+		{
+			name: "epc minimal",
+			data: EPCData{Name: "ACME", IBAN: "NL91ABNA0417164300"},
+			want: "BCD\n002\n1\nSCT\n\nACME\nNL91ABNA0417164300",
+		},
+		{
+			name: "epc bad iban",
+			data: EPCData{Name: "ACME", IBAN: "DE89370400440532013001"},
+		},
+		{
+			name: "epc bad bic",
+			data: EPCData{Name: "ACME", IBAN: "NL91ABNA0417164300", BIC: "ABNA"},
+		},
+		{
+			name: "epc bad reference",
+			data: EPCData{Name: "ACME", IBAN: "NL91ABNA0417164300", Reference: "RF19539007547034"},
+		},
+		{
+			name: "epc reference and text",
+			data: EPCData{Name: "ACME", IBAN: "NL91ABNA0417164300", Reference: "RF18539007547034", Text: "Invoice"},
+		},
+		{
+			name: "epc line break",
+			data: EPCData{Name: "ACME", IBAN: "NL91ABNA0417164300", Text: "a\nb"},
+		},
+		{
+			name: "epc three decimals",
+			data: EPCData{Name: "ACME", IBAN: "NL91ABNA0417164300", Amount: "1.005"},
+		},
+		{
+			name: "bip21",
+			data: CryptoData{Network: "bitcoin", Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", Amount: "0.00100000", Label: "Luke Jr", Message: "Donation for project xyz"},
+			want: "bitcoin:bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4?amount=0.001&label=Luke%20Jr&message=Donation%20for%20project%20xyz",
+		},
+		{
+			name: "bip21 address only",
+			data: CryptoData{Network: "Bitcoin", Address: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+			want: "bitcoin:1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		},
+		{
+			name: "bip21 bad address",
+			data: CryptoData{Network: "bitcoin", Address: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb"},
+		},
+		{
+			name: "bip21 nine decimals",
+			data: CryptoData{Network: "bitcoin", Address: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", Amount: "0.000000001"},
+		},
+		{
+			name: "eip681",
+			data: CryptoData{Network: "ethereum", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Amount: "0.5", ChainID: 137},
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed@137?value=500000000000000000",
+		},
+		{
+			name: "eip681 mainnet",
+			data: CryptoData{Network: "ethereum", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", ChainID: 1},
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		},
+		{
+			name: "eip681 bad checksum",
+			data: CryptoData{Network: "ethereum", Address: "0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		},
+		{
+			name: "eip681 with a label",
+			data: CryptoData{Network: "ethereum", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Label: "Shop"},
+		},
+		{
+			name: "unknown network",
+			data: CryptoData{Network: "dogecoin", Address: "D8vFz4p1L37jdg47HXKtSHA5uYLYxbGgPD"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.data.Payload()
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("%s: got %q, %v; want ErrInvalidPayload", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v\nwant %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -1,8 +1,56 @@
 package qrtypes
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
 
 type Repository interface {
-	SaveTypeData(ctx context.Context, qrID string, data interface{}) error
-	GetTypeData(ctx context.Context, qrID string) (map[string]interface{}, error)
+	// SaveTypeData stores (or replaces) the data behind a code
+	SaveTypeData(ctx context.Context, qrID, qrType string, data interface{}) error
+	// GetTypeData returns nil when the code has none
+	GetTypeData(ctx context.Context, qrID string) (*QRTypeData, error)
+}
+
+type repository struct {
+	pg *pgxpool.Pool
+}
+
+func NewRepository(pg *pgxpool.Pool) Repository {
+	return &repository{pg}
+}
+
+func (r *repository) SaveTypeData(ctx context.Context, qrID, qrType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = r.pg.Exec(ctx,
+		`INSERT INTO qr_type_data (qr_id, type, data, updated_at)
+         VALUES ($1, $2, $3, now())
+         ON CONFLICT (qr_id) DO UPDATE SET type = EXCLUDED.type, data = EXCLUDED.data, updated_at = now()`,
+		qrID, qrType, raw)
+	return err
+}
+
+func (r *repository) GetTypeData(ctx context.Context, qrID string) (*QRTypeData, error) {
+	var d QRTypeData
+	var raw []byte
+	err := r.pg.QueryRow(ctx,
+		`SELECT type, data, updated_at FROM qr_type_data WHERE qr_id = $1`,
+		qrID).Scan(&d.Type, &raw, &d.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &d.Metadata); err != nil {
+		return nil, err
+	}
+	return &d, nil
 }
@@ -1,9 +1,340 @@
 package qrtypes
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qr/render"
+	"qr-saas/internal/rules"
+	"qr-saas/internal/variants"
+
+	"github.com/jackc/pgx/v5"
+)
 
 type Service interface {
-	CreateWiFi(ctx context.Context, qrID string, payload WiFiData) error
-	CreateVCard(ctx context.Context, qrID string, payload VCardData) error
-	GetQRTypeData(ctx context.Context, qrID string) (interface{}, error)
+	// CreateWiFi and CreateVCard turn the code into a static code of that
+	// type: the data is stored and the encoded payload becomes what the
+	// code carries (its target_url), so images render it as is. A dynamic
+	// code with a password, limits, rules or variants isn't switched.
+	CreateWiFi(ctx context.Context, userID, qrID string, payload WiFiData) (*qr.QRCode, error)
+	// With page.Dynamic the code stays dynamic and redirects to a landing
+	// page of the data instead (a contact page with a .vcf download).
+	CreateVCard(ctx context.Context, userID, qrID string, payload VCardData, page PageOptions) (*qr.QRCode, error)
+	// CreateEvent encodes an iCalendar VEVENT; dynamic events get an event
+	// page with an .ics download
+	CreateEvent(ctx context.Context, userID, qrID string, payload EventData, page PageOptions) (*qr.QRCode, error)
+	// CreateUPI, CreateEPC and CreateCrypto encode payment requests:
+	// static only, a banking app shouldn't depend on our redirect
+	CreateUPI(ctx context.Context, userID, qrID string, payload UPIData) (*qr.QRCode, error)
+	CreateEPC(ctx context.Context, userID, qrID string, payload EPCData) (*qr.QRCode, error)
+	CreateCrypto(ctx context.Context, userID, qrID string, payload CryptoData) (*qr.QRCode, error)
+	// CreateSMS, CreateEmail, CreatePhone, CreateWhatsApp and CreateGeo
+	// encode links phones hand to the messaging, mail, phone, WhatsApp
+	// and map apps
+	CreateSMS(ctx context.Context, userID, qrID string, payload SMSData) (*qr.QRCode, error)
+	CreateEmail(ctx context.Context, userID, qrID string, payload EmailData) (*qr.QRCode, error)
+	CreatePhone(ctx context.Context, userID, qrID string, payload PhoneData) (*qr.QRCode, error)
+	CreateWhatsApp(ctx context.Context, userID, qrID string, payload WhatsAppData) (*qr.QRCode, error)
+	CreateGeo(ctx context.Context, userID, qrID string, payload GeoData) (*qr.QRCode, error)
+	// CreateAppLinks makes the code an app code: dynamic, redirecting to
+	// the App Store or Google Play by the scanning device
+	CreateAppLinks(ctx context.Context, userID, qrID string, payload AppLinksData) (*qr.QRCode, error)
+	GetQRTypeData(ctx context.Context, userID, qrID string) (*QRTypeData, error)
+}
+
+var (
+	ErrQRNotFound = errors.New("qr code not found")
+	ErrNoTypeData = errors.New("qr code has no type data")
+	// ErrTemplateNotFound is returned by Pages for a missing template or
+	// one of another category
+	ErrTemplateNotFound = errors.New("landing page template not found")
+	// ErrRedirectSettings is returned when a static type would replace a
+	// dynamic code that has settings only the redirect enforces
+	ErrRedirectSettings = errors.New("code has redirect settings a static code can't keep")
+)
+
+// Pages publishes landing pages of dynamic codes (templates.Service)
+type Pages interface {
+	// SaveInstance creates the page when urlID is empty, or replaces its
+	// data, and returns its url id
+	SaveInstance(ctx context.Context, userID, category, templateID, urlID string, data map[string]interface{}) (string, error)
+}
+
+type service struct {
+	repo      Repository
+	qrRepo    qr.Repository
+	rulesRepo rules.Repository
+	splits    variants.Repository
+	pages     Pages
+	baseURL   string
+}
+
+func NewService(repo Repository, qrRepo qr.Repository, rulesRepo rules.Repository, splits variants.Repository, pages Pages, baseURL string) Service {
+	return &service{repo: repo, qrRepo: qrRepo, rulesRepo: rulesRepo, splits: splits, pages: pages, baseURL: baseURL}
+}
+
+func (s *service) CreateWiFi(ctx context.Context, userID, qrID string, payload WiFiData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	// Keep the stored security as normalized, it's shown back in forms
+	payload.Security, _ = payload.security()
+	return s.apply(ctx, userID, qrID, TypeWiFi, content, payload)
+}
+
+func (s *service) CreateVCard(ctx context.Context, userID, qrID string, payload VCardData, page PageOptions) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	if page.Dynamic {
+		return s.publish(ctx, userID, qrID, TypeVCard, page.TemplateID, payload)
+	}
+	return s.apply(ctx, userID, qrID, TypeVCard, content, payload)
+}
+
+// apply stores data and switches the code to qrType carrying content
+func (s *service) apply(ctx context.Context, userID, qrID, qrType, content string, data interface{}) (*qr.QRCode, error) {
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if qr.IsDynamic(code.QRType) {
+		if err := s.checkRedirectSettings(ctx, code); err != nil {
+			return nil, err
+		}
+	}
+	// The payload has to fit the code's symbology (and linear ones can't
+	// hold it at all)
+	if err := render.ValidateContent(code.Symbology, content); err != nil {
+		return nil, fmt.Errorf("%s payload: %w", qrType, err)
+	}
+
+	if err := s.repo.SaveTypeData(ctx, code.ID, qrType, data); err != nil {
+		return nil, err
+	}
+
+	code.QRType = qrType
+	code.TargetURL = content
+	code.UpdatedAt = time.Now().UTC()
+	if err := s.qrRepo.Update(ctx, code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// checkRedirectSettings refuses to make a dynamic code static while it
+// has a password, limits, rules or variants: scans would no longer reach
+// the redirect that enforces them
+func (s *service) checkRedirectSettings(ctx context.Context, code *qr.QRCode) error {
+	var attached []string
+	if code.PasswordProtected {
+		attached = append(attached, "password")
+	}
+	if code.ValidFrom != nil || code.ValidUntil != nil || code.MaxScans != nil {
+		attached = append(attached, "limits")
+	}
+	rs, err := s.rulesRepo.GetRules(ctx, code.ID)
+	if err != nil {
+		return err
+	}
+	if rs != nil && len(rs.Rules) > 0 {
+		attached = append(attached, "redirect rules")
+	}
+	split, err := s.splits.GetSplit(ctx, code.ID)
+	if err != nil {
+		return err
+	}
+	if split != nil && len(split.Variants) > 0 {
+		attached = append(attached, "A/B variants")
+	}
+
+	if len(attached) > 0 {
+		return fmt.Errorf("%w: remove its %s first", ErrRedirectSettings, strings.Join(attached, ", "))
+	}
+	return nil
+}
+
+func (s *service) CreateEvent(ctx context.Context, userID, qrID string, payload EventData, page PageOptions) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	if page.Dynamic {
+		return s.publish(ctx, userID, qrID, TypeEvent, page.TemplateID, payload)
+	}
+	return s.apply(ctx, userID, qrID, TypeEvent, content, payload)
+}
+
+func (s *service) CreateUPI(ctx context.Context, userID, qrID string, payload UPIData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypeUPI, content, payload)
+}
+
+func (s *service) CreateEPC(ctx context.Context, userID, qrID string, payload EPCData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypeEPC, content, payload)
+}
+
+func (s *service) CreateCrypto(ctx context.Context, userID, qrID string, payload CryptoData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypeCrypto, content, payload)
+}
+
+func (s *service) CreateSMS(ctx context.Context, userID, qrID string, payload SMSData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypeSMS, content, payload)
+}
+
+func (s *service) CreateEmail(ctx context.Context, userID, qrID string, payload EmailData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypeEmail, content, payload)
+}
+
+func (s *service) CreatePhone(ctx context.Context, userID, qrID string, payload PhoneData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypePhone, content, payload)
+}
+
+func (s *service) CreateWhatsApp(ctx context.Context, userID, qrID string, payload WhatsAppData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypeWhatsApp, content, payload)
+}
+
+func (s *service) CreateGeo(ctx context.Context, userID, qrID string, payload GeoData) (*qr.QRCode, error) {
+	content, err := payload.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, userID, qrID, TypeGeo, content, payload)
+}
+
+func (s *service) CreateAppLinks(ctx context.Context, userID, qrID string, payload AppLinksData) (*qr.QRCode, error) {
+	links, err := payload.Normalized()
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if render.IsLinear(code.Symbology) {
+		return nil, qr.ErrLinearNeedsStatic
+	}
+
+	if err := s.repo.SaveTypeData(ctx, code.ID, TypeApp, links); err != nil {
+		return nil, err
+	}
+
+	// The redirect reads the store links from the type data, the web link
+	// stays editable as the target_url
+	code.QRType = TypeApp
+	code.TargetURL = links.WebURL
+	code.UpdatedAt = time.Now().UTC()
+	if err := s.qrRepo.Update(ctx, code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// pageKey is where the landing page of a dynamic code is kept in its
+// type data
+const pageKey = "page_url_id"
+
+// publish stores data on the code's landing page (a template of the
+// qrType category) and points the dynamic code at it. Publishing again
+// updates the same page, the printed code keeps working.
+func (s *service) publish(ctx context.Context, userID, qrID, qrType, templateID string, data interface{}) (*qr.QRCode, error) {
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if render.IsLinear(code.Symbology) {
+		return nil, qr.ErrLinearNeedsStatic
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+
+	urlID := ""
+	if prev, err := s.repo.GetTypeData(ctx, code.ID); err == nil && prev != nil && prev.Type == qrType {
+		urlID, _ = prev.Metadata[pageKey].(string)
+	}
+	urlID, err = s.pages.SaveInstance(ctx, userID, qrType, templateID, urlID, m)
+	if err != nil {
+		return nil, err
+	}
+
+	m[pageKey] = urlID
+	if err := s.repo.SaveTypeData(ctx, code.ID, qrType, m); err != nil {
+		return nil, err
+	}
+
+	code.QRType = "dynamic"
+	code.TargetURL = s.baseURL + "/t/" + urlID
+	code.UpdatedAt = time.Now().UTC()
+	if err := s.qrRepo.Update(ctx, code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (s *service) GetQRTypeData(ctx context.Context, userID, qrID string) (*QRTypeData, error) {
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.repo.GetTypeData(ctx, code.ID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrNoTypeData
+	}
+	return d, nil
 }
@@ -0,0 +1,328 @@
+package qrtypes
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Limits on repeated vCard fields, a code has to stay scannable
+const (
+	maxPhones  = 5
+	maxEmails  = 5
+	maxSocials = 8
+)
+
+var (
+	phoneRe   = regexp.MustCompile(`^\+?[0-9 ()./-]{3,30}$`)
+	networkRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,29}$`)
+)
+
+// ValidPhone reports a phone number the builders accept
+func ValidPhone(s string) bool {
+	return phoneRe.MatchString(s)
+}
+
+// ValidEmail reports a bare email address the builders accept
+func ValidEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
+
+// normalizeFormat maps the accepted spellings to a Format* constant
+func normalizeFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatVCard3, "vcard", "3", "3.0":
+		return FormatVCard3, nil
+	case FormatVCard4, "4", "4.0":
+		return FormatVCard4, nil
+	case FormatMeCard:
+		return FormatMeCard, nil
+	}
+	return "", fmt.Errorf("%w: format must be vcard3, vcard4 or mecard", ErrInvalidPayload)
+}
+
+// card is a validated VCardData, ready to encode
+type card struct {
+	full, first, last string
+	phones, emails    []TypedValue
+	v                 VCardData
+}
+
+// Payload encodes the contact in v.Format (vCard 3.0 by default, which
+// every phone camera can import)
+func (v VCardData) Payload() (string, error) {
+	format, err := normalizeFormat(v.Format)
+	if err != nil {
+		return "", err
+	}
+	c, err := v.validate()
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case FormatVCard4:
+		return c.vcard(4), nil
+	case FormatMeCard:
+		return c.mecard(), nil
+	}
+	return c.vcard(3), nil
+}
+
+// VCF is the card as a .vcf file: vCard 4.0 when asked for, 3.0 otherwise
+// (a MeCard isn't a file format)
+func (v VCardData) VCF() ([]byte, error) {
+	c, err := v.validate()
+	if err != nil {
+		return nil, err
+	}
+	version := 3
+	if format, _ := normalizeFormat(v.Format); format == FormatVCard4 {
+		version = 4
+	}
+	return []byte(c.vcard(version) + "\r\n"), nil
+}
+
+// Normalized is the validated card with the single phone and email
+// folded into the lists and the names and types filled in, as a landing
+// page shows it
+func (v VCardData) Normalized() (VCardData, error) {
+	c, err := v.validate()
+	if err != nil {
+		return v, err
+	}
+	n := c.v
+	n.FullName, n.FirstName, n.LastName = c.full, c.first, c.last
+	n.Phone, n.Email = "", ""
+	n.Phones, n.Emails = c.phones, c.emails
+	if n.Address != nil {
+		a := *n.Address
+		a.Type = addressTypes[strings.ToLower(strings.TrimSpace(a.Type))]
+		n.Address = &a
+	}
+	return n, nil
+}
+
+func (v VCardData) validate() (*card, error) {
+	c := &card{v: v}
+	c.first, c.last = strings.TrimSpace(v.FirstName), strings.TrimSpace(v.LastName)
+	c.full = strings.TrimSpace(v.FullName)
+	switch {
+	case c.full == "" && c.first == "" && c.last == "":
+		return nil, fmt.Errorf("%w: full_name is required", ErrInvalidPayload)
+	case c.full == "":
+		c.full = strings.TrimSpace(c.first + " " + c.last)
+	case c.first == "" && c.last == "":
+		c.last, c.first = splitName(c.full)
+	}
+
+	if p := strings.TrimSpace(v.Phone); p != "" {
+		c.phones = append(c.phones, TypedValue{Type: "cell", Value: p})
+	}
+	for _, p := range v.Phones {
+		c.phones = append(c.phones, TypedValue{Type: p.Type, Value: strings.TrimSpace(p.Value)})
+	}
+	if len(c.phones) > maxPhones {
+		return nil, fmt.Errorf("%w: at most %d phones", ErrInvalidPayload, maxPhones)
+	}
+	for i, p := range c.phones {
+		t, ok := phoneTypes[strings.ToLower(strings.TrimSpace(p.Type))]
+		if !ok {
+			return nil, fmt.Errorf("%w: phone type must be cell, work, home, fax or other", ErrInvalidPayload)
+		}
+		if !ValidPhone(p.Value) {
+			return nil, fmt.Errorf("%w: invalid phone number %q", ErrInvalidPayload, p.Value)
+		}
+		c.phones[i].Type = t
+	}
+
+	if e := strings.TrimSpace(v.Email); e != "" {
+		c.emails = append(c.emails, TypedValue{Type: "work", Value: e})
+	}
+	for _, e := range v.Emails {
+		c.emails = append(c.emails, TypedValue{Type: e.Type, Value: strings.TrimSpace(e.Value)})
+	}
+	if len(c.emails) > maxEmails {
+		return nil, fmt.Errorf("%w: at most %d emails", ErrInvalidPayload, maxEmails)
+	}
+	for i, e := range c.emails {
+		t, ok := emailTypes[strings.ToLower(strings.TrimSpace(e.Type))]
+		if !ok {
+			return nil, fmt.Errorf("%w: email type must be work, home or other", ErrInvalidPayload)
+		}
+		if !ValidEmail(e.Value) {
+			return nil, fmt.Errorf("%w: invalid email %q", ErrInvalidPayload, e.Value)
+		}
+		c.emails[i].Type = t
+	}
+
+	if v.Website != "" && !isWebURL(v.Website) {
+		return nil, fmt.Errorf("%w: website must be an http(s) URL", ErrInvalidPayload)
+	}
+	if v.PhotoURL != "" && !isWebURL(v.PhotoURL) {
+		return nil, fmt.Errorf("%w: photo_url must be an http(s) URL", ErrInvalidPayload)
+	}
+	if len(v.Socials) > maxSocials {
+		return nil, fmt.Errorf("%w: at most %d social profiles", ErrInvalidPayload, maxSocials)
+	}
+	c.v.Socials = append([]SocialProfile(nil), v.Socials...)
+	for i, s := range v.Socials {
+		network := strings.ToLower(strings.TrimSpace(s.Network))
+		if !networkRe.MatchString(network) || !isWebURL(s.URL) {
+			return nil, fmt.Errorf("%w: social profiles need a network name and an http(s) URL", ErrInvalidPayload)
+		}
+		c.v.Socials[i].Network = network
+	}
+	if a := v.Address; a != nil {
+		if _, ok := addressTypes[strings.ToLower(strings.TrimSpace(a.Type))]; !ok {
+			return nil, fmt.Errorf("%w: address type must be work, home or other", ErrInvalidPayload)
+		}
+	}
+	return c, nil
+}
+
+// Type names accepted in requests, normalized
+var (
+	phoneTypes   = map[string]string{"": "cell", "cell": "cell", "mobile": "cell", "work": "work", "home": "home", "fax": "fax", "other": "other"}
+	emailTypes   = map[string]string{"": "work", "work": "work", "home": "home", "other": "other"}
+	addressTypes = map[string]string{"": "work", "work": "work", "home": "home", "other": "other"}
+)
+
+// vcard encodes a vCard 3.0 or 4.0. Long lines aren't folded: readers
+// unfold anyway and the fold bytes would only grow the code.
+func (c *card) vcard(version int) string {
+	lines := []string{"BEGIN:VCARD", fmt.Sprintf("VERSION:%d.0", version)}
+	add := func(prop, value string) {
+		if value != "" {
+			lines = append(lines, prop+":"+value)
+		}
+	}
+	// typed renders the TYPE parameter the way each version spells it
+	typed := func(prop string, types ...string) string {
+		var ts []string
+		for _, t := range types {
+			if t == "other" || t == "" {
+				continue
+			}
+			if version == 3 {
+				t = strings.ToUpper(t)
+			}
+			ts = append(ts, t)
+		}
+		if len(ts) == 0 {
+			return prop
+		}
+		return prop + ";TYPE=" + strings.Join(ts, ",")
+	}
+
+	add("N", vcardEscape(c.last)+";"+vcardEscape(c.first)+";;;")
+	add("FN", vcardEscape(c.full))
+	add("ORG", vcardEscape(strings.TrimSpace(c.v.Company)))
+	add("TITLE", vcardEscape(strings.TrimSpace(c.v.Title)))
+	for _, p := range c.phones {
+		types := []string{p.Type}
+		if version == 3 && p.Type != "cell" && p.Type != "fax" {
+			types = append(types, "voice")
+		}
+		add(typed("TEL", types...), vcardEscape(p.Value))
+	}
+	for _, e := range c.emails {
+		types := []string{e.Type}
+		if version == 3 {
+			types = append([]string{"internet"}, types...)
+		}
+		add(typed("EMAIL", types...), vcardEscape(e.Value))
+	}
+	if a := c.v.Address; a != nil {
+		parts := []string{"", "", a.Street, a.City, a.Region, a.PostalCode, a.Country}
+		for i := range parts {
+			parts[i] = vcardEscape(strings.TrimSpace(parts[i]))
+		}
+		if adr := strings.Join(parts, ";"); strings.Trim(adr, ";") != "" {
+			add(typed("ADR", addressTypes[strings.ToLower(strings.TrimSpace(a.Type))]), adr)
+		}
+	}
+	// URIs are not text values, their commas stay as they are
+	add("URL", c.v.Website)
+	for _, s := range c.v.Socials {
+		add("X-SOCIALPROFILE;TYPE="+s.Network, s.URL)
+	}
+	if c.v.PhotoURL != "" {
+		if version == 3 {
+			add("PHOTO;VALUE=URI", c.v.PhotoURL)
+		} else {
+			add("PHOTO", c.v.PhotoURL)
+		}
+	}
+	add("NOTE", vcardEscape(strings.TrimSpace(c.v.Note)))
+	lines = append(lines, "END:VCARD")
+
+	return strings.Join(lines, "\r\n")
+}
+
+// mecard encodes the compact DoCoMo MECARD format
+func (c *card) mecard() string {
+	var b strings.Builder
+	b.WriteString("MECARD:")
+	add := func(prop, value string) {
+		if value != "" {
+			b.WriteString(prop + ":" + value + ";")
+		}
+	}
+
+	// The comma splits family and given name, so it can't be in either
+	unComma := strings.NewReplacer(",", " ")
+	name := mecardEscape(unComma.Replace(c.last))
+	if c.first != "" {
+		name += "," + mecardEscape(unComma.Replace(c.first))
+	}
+	add("N", name)
+	add("ORG", mecardEscape(strings.TrimSpace(c.v.Company)))
+	for _, p := range c.phones {
+		add("TEL", mecardEscape(p.Value))
+	}
+	for _, e := range c.emails {
+		add("EMAIL", mecardEscape(e.Value))
+	}
+	if a := c.v.Address; a != nil {
+		// Readers show ADR as one line rather than DoCoMo's 7 fields
+		var parts []string
+		for _, p := range []string{a.Street, a.City, a.Region, a.PostalCode, a.Country} {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		add("ADR", mecardEscape(strings.Join(parts, ", ")))
+	}
+	add("URL", mecardEscape(c.v.Website))
+	add("NOTE", mecardEscape(strings.TrimSpace(c.v.Note)))
+	b.WriteString(";")
+	return b.String()
+}
+
+// splitName guesses the structured name from a full name: "Doe, Jane" as
+// written, otherwise the last word is the family name
+func splitName(full string) (family, given string) {
+	if family, given, ok := strings.Cut(full, ","); ok {
+		return strings.TrimSpace(family), strings.TrimSpace(given)
+	}
+	parts := strings.Fields(full)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[len(parts)-1], strings.Join(parts[:len(parts)-1], " ")
+}
+
+var (
+	vcardEscaper  = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	mecardEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ":", `\:`, "\r\n", " ", "\n", " ", "\r", " ")
+)
+
+func vcardEscape(s string) string {
+	return vcardEscaper.Replace(s)
+}
+
+func mecardEscape(s string) string {
+	return mecardEscaper.Replace(s)
+}
@@ -0,0 +1,109 @@
+package qrtypes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// contact has every field that needs escaping in some format
+var contact = VCardData{
+	FullName: "Doe, Jane",
+	Company:  "ACME; Inc, Ltd",
+	Title:    `CEO\CTO`,
+	Phone:    "+1 555 0100",
+	Phones:   []TypedValue{{Type: "work", Value: "+1 555 0199"}},
+	Email:    "jane@acme.io",
+	Address:  &Address{Street: "1 Main St; Apt 2", City: "Springfield", Country: "US"},
+	Website:  "https://acme.io/a,b",
+	Note:     "Line 1\nLine 2\rLine 3",
+}
+
+func TestVCardPayload(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", strings.Join([]string{
+			"BEGIN:VCARD",
+			"VERSION:3.0",
+			"N:Doe;Jane;;;",
+			`FN:Doe\, Jane`,
+			`ORG:ACME\; Inc\, Ltd`,
+			`TITLE:CEO\\CTO`,
+			"TEL;TYPE=CELL:+1 555 0100",
+			"TEL;TYPE=WORK,VOICE:+1 555 0199",
+			"EMAIL;TYPE=INTERNET,WORK:jane@acme.io",
+			`ADR;TYPE=WORK:;;1 Main St\; Apt 2;Springfield;;;US`,
+			"URL:https://acme.io/a,b",
+			`NOTE:Line 1\nLine 2\nLine 3`,
+			"END:VCARD",
+		}, "\r\n")},
+		{"vcard4", strings.Join([]string{
+			"BEGIN:VCARD",
+			"VERSION:4.0",
+			"N:Doe;Jane;;;",
+			`FN:Doe\, Jane`,
+			`ORG:ACME\; Inc\, Ltd`,
+			`TITLE:CEO\\CTO`,
+			"TEL;TYPE=cell:+1 555 0100",
+			"TEL;TYPE=work:+1 555 0199",
+			"EMAIL;TYPE=work:jane@acme.io",
+			`ADR;TYPE=work:;;1 Main St\; Apt 2;Springfield;;;US`,
+			"URL:https://acme.io/a,b",
+			`NOTE:Line 1\nLine 2\nLine 3`,
+			"END:VCARD",
+		}, "\r\n")},
+		{"mecard", `MECARD:N:Doe,Jane;ORG:ACME\; Inc, Ltd;TEL:+1 555 0100;TEL:+1 555 0199;EMAIL:jane@acme.io;` +
+			`ADR:1 Main St\; Apt 2, Springfield, US;URL:https\://acme.io/a,b;NOTE:Line 1 Line 2 Line 3;;`},
+	}
+	for _, tt := range tests {
+		v := contact
+		v.Format = tt.format
+		got, err := v.Payload()
+		if err != nil || got != tt.want {
+			t.Errorf("format %q: got %q, %v\nwant %q", tt.format, got, err, tt.want)
+		}
+	}
+}
+
+func TestVCardNames(t *testing.T) {
+	tests := []struct {
+		data VCardData
+		want string // the N and FN lines
+	}{
+		{VCardData{FullName: "Jane Q Doe"}, "N:Doe;Jane Q;;;\r\nFN:Jane Q Doe"},
+		{VCardData{FullName: "Cher"}, "N:Cher;;;;\r\nFN:Cher"},
+		{VCardData{FirstName: "Jane", LastName: "Doe"}, "N:Doe;Jane;;;\r\nFN:Jane Doe"},
+	}
+	for _, tt := range tests {
+		got, err := tt.data.Payload()
+		if err != nil || !strings.Contains(got, tt.want) {
+			t.Errorf("%+v: got %q, %v; want it to contain %q", tt.data, got, err, tt.want)
+		}
+	}
+}
+
+func TestVCardInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data VCardData
+	}{
+		{"no name", VCardData{Phone: "+1 555 0100"}},
+		{"bad format", VCardData{FullName: "Jane", Format: "vcard2"}},
+		{"bad phone", VCardData{FullName: "Jane", Phone: "call me"}},
+		{"bad phone type", VCardData{FullName: "Jane", Phones: []TypedValue{{Type: "pager", Value: "+1 555 0100"}}}},
+		{"bad email", VCardData{FullName: "Jane", Email: "Jane <jane@acme.io>"}},
+		{"script website", VCardData{FullName: "Jane", Website: "javascript:alert(1)"}},
+		{"bad social", VCardData{FullName: "Jane", Socials: []SocialProfile{{Network: "x", URL: "x.com/jane"}}}},
+		{"bad address type", VCardData{FullName: "Jane", Address: &Address{City: "Springfield", Type: "office"}}},
+		{"too many phones", VCardData{FullName: "Jane", Phone: "1001", Phones: []TypedValue{
+			{Value: "1002"}, {Value: "1003"}, {Value: "1004"}, {Value: "1005"}, {Value: "1006"},
+		}}},
+	}
+	for _, tt := range tests {
+		if got, err := tt.data.Payload(); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("%s: got %q, %v; want ErrInvalidPayload", tt.name, got, err)
+		}
+	}
+}
@@ -1,7 +1,14 @@
 package redirect
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -9,6 +16,8 @@ import (
 func RegisterRoutes(r *gin.Engine, svc *Service) {
 	h := &Handler{svc: svc}
 	r.GET("/r/:code", h.RedirectQR)
+	r.POST("/r/:code", h.Unlock)
+	r.GET("/r/:code/convert", h.Convert)
 }
 
 type Handler struct {
@@ -22,18 +31,187 @@ type Handler struct {
 // @Param code path string true "QR Code Short ID"
 // @Success 302 {string} string "redirect"
 // @Failure 404 {object} map[string]string
+// @Failure 401 {string} string "password form of a protected code"
+// @Failure 410 {string} string "expired or scan limit reached, when the code has no fallback url"
 // @Router /r/{code} [get]
 func (h *Handler) RedirectQR(c *gin.Context) {
-	code := c.Param("code")
-	ip := c.ClientIP()
-	ua := c.Request.UserAgent()
-	ref := c.Request.Referer()
+	v := Visit{
+		ShortCode:      c.Param("code"),
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		Referer:        c.Request.Referer(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Country:        country(c),
+		Time:           time.Now().UTC(),
+	}
+	v.Variant, _ = c.Cookie(variantCookie(v.ShortCode))
+	v.Pass, _ = c.Cookie(passCookie(v.ShortCode))
+
+	res, err := h.svc.ResolveAndLog(c.Request.Context(), v)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "QR code not found"})
+		return
+	}
+
+	if res.Password != nil {
+		renderPassword(c, res.Password)
+		return
+	}
+	if res.Page != nil {
+		renderPage(c, res.Page)
+		return
+	}
+	if res.Variant != "" {
+		setVariantCookie(c, v.ShortCode, res.Variant)
+	}
+	c.Redirect(http.StatusFound, res.TargetURL)
+}
+
+// Unlock godoc
+// @Summary Enter the password of a protected code
+// @Description Checks the password form; the right password sets a signed cookie valid for an hour and continues to the redirect. Failed attempts are limited per code and IP.
+// @Tags Redirect
+// @Accept x-www-form-urlencoded
+// @Param code path string true "QR Code Short ID"
+// @Param password formData string true "password"
+// @Success 303 {string} string "redirect"
+// @Failure 401 {string} string "incorrect password"
+// @Failure 429 {string} string "too many attempts"
+// @Router /r/{code} [post]
+func (h *Handler) Unlock(c *gin.Context) {
+	v := Visit{
+		ShortCode: c.Param("code"),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Time:      time.Now().UTC(),
+	}
 
-	target, err := h.svc.ResolveAndLog(c.Request.Context(), code, ip, ua, ref)
+	token, form, err := h.svc.Unlock(c.Request.Context(), v, c.PostForm("password"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "QR code not found"})
 		return
 	}
+	if form != nil {
+		renderPassword(c, form)
+		return
+	}
+
+	if token != "" {
+		setPassCookie(c, v.ShortCode, token)
+	}
+	c.Redirect(http.StatusSeeOther, "/r/"+v.ShortCode)
+}
+
+// renderPassword shows the password form of a protected code
+func renderPassword(c *gin.Context, page *PasswordPage) {
+	tpl, err := template.ParseFiles("internal/redirect/views/password.html")
+	if err != nil {
+		fmt.Printf("❌ Password page: %v\n", err)
+		c.String(page.Status, "This QR code is password protected.")
+		return
+	}
+
+	var out bytes.Buffer
+	if err := tpl.Execute(&out, page); err != nil {
+		fmt.Printf("❌ Password page: %v\n", err)
+		c.String(page.Status, "This QR code is password protected.")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(page.Status, "text/html; charset=utf-8", out.Bytes())
+}
 
-	c.Redirect(http.StatusFound, target)
+// renderPage shows the page of a code that doesn't redirect
+func renderPage(c *gin.Context, page *Page) {
+	tpl, err := template.ParseFiles("internal/redirect/views/unavailable.html")
+	if err != nil {
+		fmt.Printf("❌ Unavailable page: %v\n", err)
+		c.String(page.Status, page.Message)
+		return
+	}
+
+	var out bytes.Buffer
+	if err := tpl.Execute(&out, page); err != nil {
+		fmt.Printf("❌ Unavailable page: %v\n", err)
+		c.String(page.Status, page.Message)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(page.Status, "text/html; charset=utf-8", out.Bytes())
+}
+
+// pixel is a transparent 1x1 GIF
+var pixel = []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+
+// Convert godoc
+// @Summary Report a conversion
+// @Description Tracking pixel for landing pages: records a conversion of the scanner for its A/B variant (the variant query, its cookie, or the one its IP and user agent hash to)
+// @Tags Redirect
+// @Param code path string true "QR Code Short ID"
+// @Param variant query string false "variant id, when the landing page knows it"
+// @Produce image/gif
+// @Success 200 {string} string "pixel"
+// @Failure 404 {object} map[string]string
+// @Router /r/{code}/convert [get]
+func (h *Handler) Convert(c *gin.Context) {
+	v := Visit{
+		ShortCode: c.Param("code"),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Variant:   c.Query("variant"),
+		Time:      time.Now().UTC(),
+	}
+	if v.Variant == "" {
+		v.Variant, _ = c.Cookie(variantCookie(v.ShortCode))
+	}
+
+	if err := h.svc.LogConversion(c.Request.Context(), v); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "QR code not found"})
+			return
+		}
+		fmt.Printf("❌ Conversion of %s not logged: %v\n", v.ShortCode, err)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/gif", pixel)
+}
+
+// variantCookie is per code, a scanner can be in several tests
+func variantCookie(shortCode string) string {
+	return "qrv_" + shortCode
+}
+
+// setVariantCookie keeps the scanner's variant for 30 days
+func setVariantCookie(c *gin.Context, shortCode, variant string) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(variantCookie(shortCode), variant, 30*24*60*60, "/r/"+shortCode, "", secure, true)
+}
+
+// passCookie is per code, like the variant cookie
+func passCookie(shortCode string) string {
+	return "qrp_" + shortCode
+}
+
+// setPassCookie lets the scanner through for passTTL
+func setPassCookie(c *gin.Context, shortCode, token string) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(passCookie(shortCode), token, int(passTTL.Seconds()), "/r/"+shortCode, "", secure, true)
+}
+
+// countryHeaders carry the visitor's country when a CDN or proxy in
+// front of us looked it up
+var countryHeaders = []string{"CF-IPCountry", "CloudFront-Viewer-Country", "X-Country-Code"}
+
+var countryRe = regexp.MustCompile(`^[A-Z]{2}$`)
+
+func country(c *gin.Context) string {
+	for _, h := range countryHeaders {
+		// Cloudflare sends XX for unknown and T1 for Tor
+		if v := strings.ToUpper(c.GetHeader(h)); countryRe.MatchString(v) && v != "XX" && v != "T1" {
+			return v
+		}
+	}
+	return ""
 }
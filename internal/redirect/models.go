@@ -0,0 +1,44 @@
+package redirect
+
+import "time"
+
+// Visit is one scan of a short link, as the redirect sees it
+type Visit struct {
+	ShortCode      string
+	IP             string
+	UserAgent      string
+	Referer        string
+	AcceptLanguage string
+	Country        string // ISO 3166 alpha-2 from the CDN, empty when unknown
+	Variant        string // A/B variant the scanner was assigned before (its cookie)
+	Pass           string // password cookie of a protected code
+	Time           time.Time
+}
+
+// visitorKey identifies a scanner without cookies
+func (v Visit) visitorKey() string {
+	return v.IP + "|" + v.UserAgent
+}
+
+// Resolution is where a scan goes
+type Resolution struct {
+	TargetURL string
+	Variant   string        // A/B variant assigned, to keep in a cookie
+	Page      *Page         // shown instead of redirecting, when set
+	Password  *PasswordPage // asks for the password of a protected code
+}
+
+// Page tells the scanner why a code doesn't redirect
+type Page struct {
+	Status  int
+	Title   string
+	Message string
+}
+
+// PasswordPage is the form of a protected code, in its owner's branding
+type PasswordPage struct {
+	Status    int
+	BrandName string
+	LogoURL   string
+	Error     string // why the last attempt failed
+}
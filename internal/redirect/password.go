@@ -0,0 +1,117 @@
+package redirect
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"qr-saas/internal/qr"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// passTTL is how long the right password lets a scanner through
+	passTTL = time.Hour
+
+	// Failed attempts before the form is locked, for failedWindow after
+	// the last one: per code and IP, and per code from any IP (guessing
+	// spread over many addresses)
+	maxFailedAttempts     = 5
+	maxCodeFailedAttempts = 50
+	failedWindow          = 15 * time.Minute
+)
+
+// Unlock checks the password entered for a protected code. It returns
+// the token of the scanner's password cookie, or the form again with
+// what went wrong.
+func (s *Service) Unlock(ctx context.Context, v Visit, password string) (string, *PasswordPage, error) {
+	if v.Time.IsZero() {
+		v.Time = time.Now().UTC()
+	}
+	qrData, err := s.qrRepo.GetByShortCode(ctx, v.ShortCode)
+	if err != nil {
+		return "", nil, err
+	}
+	if qrData == nil || !qrData.IsActive || !qr.IsDynamic(qrData.QRType) {
+		return "", nil, ErrNotFound
+	}
+	if !qrData.PasswordProtected {
+		return "", nil, nil
+	}
+
+	codeKey := "qrpw:" + qrData.ID
+	ipKey := codeKey + ":" + v.IP
+	// Without redis attempts aren't limited, bcrypt still slows guessing
+	if s.failures(ctx, ipKey) >= maxFailedAttempts || s.failures(ctx, codeKey) >= maxCodeFailedAttempts {
+		page := s.passwordPage(ctx, qrData)
+		page.Status = http.StatusTooManyRequests
+		page.Error = "Too many attempts. Please try again later."
+		return "", page, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(qrData.PasswordHash), []byte(password)) != nil {
+		pipe := s.rdb.TxPipeline()
+		for _, key := range []string{ipKey, codeKey} {
+			pipe.Incr(ctx, key)
+			pipe.Expire(ctx, key, failedWindow)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			fmt.Printf("⚠️ Failed attempt on QR %s not counted: %v\n", qrData.ID, err)
+		}
+		page := s.passwordPage(ctx, qrData)
+		page.Error = "Incorrect password."
+		return "", page, nil
+	}
+
+	s.rdb.Del(ctx, ipKey)
+	return s.passToken(qrData, v.Time.Add(passTTL)), nil, nil
+}
+
+// failures counts the failed attempts under key, 0 when unknown
+func (s *Service) failures(ctx context.Context, key string) int {
+	n, err := s.rdb.Get(ctx, key).Int()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// passToken lets the scanner through until exp. The password hash is
+// signed along, changing the password locks everyone out again.
+func (s *Service) passToken(qrData *qr.QRCode, exp time.Time) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "qr-pass|%s|%d|%s", qrData.ID, exp.Unix(), qrData.PasswordHash)
+	return strconv.FormatInt(exp.Unix(), 10) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) validPass(qrData *qr.QRCode, token string, now time.Time) bool {
+	expStr, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || now.Unix() >= exp {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(s.passToken(qrData, time.Unix(exp, 0))))
+}
+
+// passwordPage is the form of a protected code, with its owner's brand
+// when there is one
+func (s *Service) passwordPage(ctx context.Context, qrData *qr.QRCode) *PasswordPage {
+	page := &PasswordPage{Status: http.StatusUnauthorized}
+	brand, err := s.brands.GetByUserID(ctx, qrData.UserID)
+	if err != nil || brand == nil {
+		return page
+	}
+	page.BrandName = brand.BrandName
+	page.LogoURL = brand.LogoURL
+	return page
+}
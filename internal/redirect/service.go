@@ -2,59 +2,82 @@ package redirect
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"qr-saas/internal/analytics"
 	"qr-saas/internal/qr"
+	"qr-saas/internal/qrtypes"
+	"qr-saas/internal/rules"
+	"qr-saas/internal/settings"
+	"qr-saas/internal/variants"
 
 	"github.com/google/uuid"        // Use UUIDs for unique events
 	"github.com/mileusna/useragent" // <--- NEW: Import this
+	"github.com/redis/go-redis/v9"
 )
 
 var ErrNotFound = errors.New("qr code not found or inactive")
 
 type Service struct {
 	qrRepo    qr.Repository
+	typesRepo qrtypes.Repository // store links of app codes
+	rulesRepo rules.Repository
+	splits    variants.Repository
+	brands    settings.Repository // owner branding of password forms
 	analytics analytics.Service
+	rdb       *redis.Client // failed password attempts
+	secret    []byte        // signs password cookies
 }
 
-func NewService(qrRepo qr.Repository, analyticsSvc analytics.Service) *Service {
+func NewService(qrRepo qr.Repository, typesRepo qrtypes.Repository, rulesRepo rules.Repository, splits variants.Repository, brands settings.Repository, analyticsSvc analytics.Service, rdb *redis.Client, secret string) *Service {
 	return &Service{
 		qrRepo:    qrRepo,
+		typesRepo: typesRepo,
+		rulesRepo: rulesRepo,
+		splits:    splits,
+		brands:    brands,
 		analytics: analyticsSvc,
+		rdb:       rdb,
+		secret:    []byte(secret),
 	}
 }
 
-func (s *Service) ResolveAndLog(ctx context.Context, shortCode string, ip string, uaString string, referer string) (string, error) {
+func (s *Service) ResolveAndLog(ctx context.Context, v Visit) (*Resolution, error) {
+	if v.Time.IsZero() {
+		v.Time = time.Now().UTC()
+	}
 
 	// 1. Database Lookup (Postgres)
-	qrData, err := s.qrRepo.GetByShortCode(ctx, shortCode)
+	qrData, err := s.qrRepo.GetByShortCode(ctx, v.ShortCode)
 	if err != nil {
 		fmt.Printf("❌ Database Error: %v\n", err)
-		return "", err
+		return nil, err
 	}
 
 	// 2. Validation
 	if qrData == nil || !qrData.IsActive {
 		fmt.Println("❌ QR not found or inactive")
-		return "", ErrNotFound
+		return nil, ErrNotFound
 	}
 
 	fmt.Printf("ℹ️ QR Data Found - ID: %s | Type: '%s'\n", qrData.ID, qrData.QRType)
 
 	// 3. Dynamic Check (Only dynamic QRs track analytics usually)
-	targetURL := qrData.TargetURL
-	if qrData.QRType == "dynamic" {
-		if targetURL == "" {
-			return "", ErrNotFound
+	res := &Resolution{TargetURL: qrData.TargetURL}
+	if qr.IsDynamic(qrData.QRType) {
+		if res.TargetURL == "" {
+			return nil, ErrNotFound
 		}
 
 		// ---------------------------------------------------------
 		// DATA ENRICHMENT (Parsing User Agent)
 		// ---------------------------------------------------------
-		ua := useragent.Parse(uaString)
+		ua := useragent.Parse(v.UserAgent)
 
 		deviceType := "Desktop"
 		if ua.Mobile {
@@ -65,23 +88,60 @@ func (s *Service) ResolveAndLog(ctx context.Context, shortCode string, ip string
 			deviceType = "Bot"
 		}
 
+		// Outside its window or over its scan cap the code gets its
+		// fallback, and the scan isn't logged. Dead codes say so before
+		// asking for a password.
+		if limit := qrData.Unavailable(v.Time); limit != "" {
+			fmt.Printf("ℹ️ QR %s not redirecting: %s\n", qrData.ID, limit)
+			return fallback(qrData, limit), nil
+		}
+
+		// Protected codes go nowhere (nor use up scans) before the password
+		if qrData.PasswordProtected && !s.validPass(qrData, v.Pass, v.Time) {
+			return &Resolution{Password: s.passwordPage(ctx, qrData)}, nil
+		}
+
+		// Concurrent scans may have used up the cap since the lookup
+		if !s.claimScan(ctx, qrData, ua.Bot) {
+			fmt.Printf("ℹ️ QR %s not redirecting: %s\n", qrData.ID, qr.LimitScanLimit)
+			return fallback(qrData, qr.LimitScanLimit), nil
+		}
+
+		// Rules come first, then an A/B split, then app codes send phones
+		// to their store
+		if dest, ok := s.ruleTarget(ctx, qrData, v, deviceType, ua); ok {
+			res.TargetURL = dest
+		} else if variant, ok := s.assignVariant(ctx, qrData, v); ok {
+			res.TargetURL = variant.Destination
+			res.Variant = variant.ID
+		} else if qrData.QRType == qrtypes.TypeApp {
+			res.TargetURL = s.appTarget(ctx, qrData, ua.OS)
+		}
+
+		country := v.Country
+		if country == "" {
+			country = "Unknown"
+		}
+
 		// 4. Construct Event
 		ev := analytics.ScanEvent{
 			EventID:   uuid.NewString(), // Generate a real UUID
 			QRID:      qrData.ID,
 			UserID:    qrData.UserID, // Important for billing/analytics
-			ScannedAt: time.Now().UTC(),
-			IP:        ip,
-			UserAgent: uaString,
-			Referer:   referer,
+			ScannedAt: v.Time,
+			IP:        v.IP,
+			UserAgent: v.UserAgent,
+			Referer:   v.Referer,
+			Variant:   res.Variant,
 
 			// Parsed Data
 			DeviceType: deviceType,
 			OS:         ua.OS,   // e.g., "Windows 10", "iOS"
 			Browser:    ua.Name, // e.g., "Chrome", "Firefox"
 
+			// Country comes from the CDN header when there is one
 			// TODO: GeoIP Lookup (Requires MaxMind DB or external API)
-			Country: "Unknown",
+			Country: country,
 			City:    "Unknown",
 		}
 
@@ -99,10 +159,138 @@ func (s *Service) ResolveAndLog(ctx context.Context, shortCode string, ip string
 			}
 		}()
 
-		return targetURL, nil
+		return res, nil
 	}
 
 	// For static QR codes, we do not log analytics
 	fmt.Println("⚠️ Skipping analytics: QR Type is not 'dynamic'")
-	return targetURL, nil
+	return res, nil
+}
+
+// LogConversion records that a scanner of the code converted, for the
+// variant it was assigned (v.Variant, else the one its IP and user agent
+// hash to)
+func (s *Service) LogConversion(ctx context.Context, v Visit) error {
+	if v.Time.IsZero() {
+		v.Time = time.Now().UTC()
+	}
+	qrData, err := s.qrRepo.GetByShortCode(ctx, v.ShortCode)
+	if err != nil {
+		return err
+	}
+	if qrData == nil || !qrData.IsActive || !qr.IsDynamic(qrData.QRType) {
+		return ErrNotFound
+	}
+
+	variant := ""
+	if split, err := s.splits.GetSplit(ctx, qrData.ID); err == nil {
+		if known, ok := split.Find(v.Variant); ok {
+			variant = known.ID
+		} else if assigned, ok := split.Assign("", v.visitorKey()); ok {
+			variant = assigned.ID
+		}
+	}
+
+	sum := sha256.Sum256([]byte(v.visitorKey()))
+	return s.analytics.InsertConversion(ctx, analytics.ConversionEvent{
+		EventID:     uuid.NewString(),
+		QRID:        qrData.ID,
+		UserID:      qrData.UserID,
+		Variant:     variant,
+		Visitor:     hex.EncodeToString(sum[:16]),
+		ConvertedAt: v.Time,
+	})
+}
+
+// claimScan counts the scan against max_scans and reports whether it
+// was within the cap. Bots (link previews) don't use it up.
+func (s *Service) claimScan(ctx context.Context, qrData *qr.QRCode, bot bool) bool {
+	if qrData.MaxScans == nil || bot {
+		return true
+	}
+	ok, err := s.qrRepo.ClaimScan(ctx, qrData.ID)
+	if err != nil {
+		// Letting a scan through beats failing it
+		fmt.Printf("⚠️ Scan of QR %s not counted: %v\n", qrData.ID, err)
+		return true
+	}
+	return ok
+}
+
+// defaultPages are shown for limits without a fallback
+var defaultPages = map[string]Page{
+	qr.LimitNotStarted: {Status: http.StatusNotFound, Title: "Not available yet", Message: "This QR code isn't active yet. Please try again later."},
+	qr.LimitExpired:    {Status: http.StatusGone, Title: "QR code expired", Message: "This QR code is no longer active."},
+	qr.LimitScanLimit:  {Status: http.StatusGone, Title: "QR code unavailable", Message: "This QR code has reached its scan limit."},
+}
+
+// fallback is where a scan stopped by limit goes: the fallback url, or
+// a page with the fallback's texts over the defaults
+func fallback(qrData *qr.QRCode, limit string) *Resolution {
+	page := defaultPages[limit]
+	if fb := qrData.Fallbacks.For(limit); fb != nil {
+		if fb.URL != "" {
+			return &Resolution{TargetURL: fb.URL}
+		}
+		if fb.Title != "" {
+			page.Title = fb.Title
+		}
+		if fb.Message != "" {
+			page.Message = fb.Message
+		}
+	}
+	return &Resolution{Page: &page}
+}
+
+// assignVariant picks the scanner's variant when the code is split
+func (s *Service) assignVariant(ctx context.Context, qrData *qr.QRCode, v Visit) (variants.Variant, bool) {
+	split, err := s.splits.GetSplit(ctx, qrData.ID)
+	if err != nil {
+		fmt.Printf("⚠️ Variants of QR %s unavailable: %v\n", qrData.ID, err)
+		return variants.Variant{}, false
+	}
+	return split.Assign(v.Variant, v.visitorKey())
+}
+
+// ruleTarget is the destination of the code's first matching redirect
+// rule. Without rules, or when they can't be read, the code redirects
+// as if it had none.
+func (s *Service) ruleTarget(ctx context.Context, qrData *qr.QRCode, v Visit, deviceType string, ua useragent.UserAgent) (string, bool) {
+	rs, err := s.rulesRepo.GetRules(ctx, qrData.ID)
+	if err != nil {
+		fmt.Printf("⚠️ Redirect rules of QR %s unavailable: %v\n", qrData.ID, err)
+		return "", false
+	}
+	return rs.Match(rules.Visitor{
+		DeviceType: deviceType,
+		OS:         ua.OS,
+		Browser:    ua.Name,
+		Language:   rules.PreferredLanguage(v.AcceptLanguage),
+		Country:    v.Country,
+		Time:       v.Time,
+	})
+}
+
+// appTarget is the store link of an app code for the OS, or its
+// target_url when there's none (or the links can't be read: a web page
+// beats a failed scan)
+func (s *Service) appTarget(ctx context.Context, qrData *qr.QRCode, os string) string {
+	d, err := s.typesRepo.GetTypeData(ctx, qrData.ID)
+	if err != nil {
+		fmt.Printf("⚠️ App links of QR %s unavailable: %v\n", qrData.ID, err)
+		return qrData.TargetURL
+	}
+	if d == nil || d.Type != qrtypes.TypeApp {
+		// No store links saved (yet), the web link is all there is
+		return qrData.TargetURL
+	}
+	var links qrtypes.AppLinksData
+	if err := d.Decode(&links); err != nil {
+		fmt.Printf("⚠️ App links of QR %s unreadable: %v\n", qrData.ID, err)
+		return qrData.TargetURL
+	}
+	if store := links.StoreURL(os); store != "" {
+		return store
+	}
+	return qrData.TargetURL
 }
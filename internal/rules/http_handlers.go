@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	svc Service
+}
+
+func RegisterRoutes(r *gin.RouterGroup, svc Service) {
+	h := &Handler{svc}
+
+	r.GET("/:id", h.GetRules)
+	r.PUT("/:id", h.SetRules)
+}
+
+// @Summary Get the redirect rules of a code
+// @Tags Redirect Rules
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "QR ID"
+// @Success 200 {object} RuleSet
+// @Router /api/qr/rules/{id} [get]
+func (h *Handler) GetRules(c *gin.Context) {
+	rs, err := h.svc.GetRules(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, rs)
+}
+
+// @Summary Set the redirect rules of a code
+// @Description Replaces the ordered rules of a dynamic code. On a scan the first rule whose conditions (device, OS, browser, language, country, weekday, time window) all match picks the destination; the code's target_url is the fallback. Weekdays and times are local to timezone. An empty list removes the rules.
+// @Tags Redirect Rules
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body SetRulesRequest true "rules"
+// @Success 200 {object} RuleSet
+// @Router /api/qr/rules/{id} [put]
+func (h *Handler) SetRules(c *gin.Context) {
+	var req SetRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	rs, err := h.svc.SetRules(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, rs)
+}
+
+// respondError writes the response for err and reports whether there was one
+func respondError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrQRNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidRules), errors.Is(err, ErrNotDynamic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+	}
+	return true
+}
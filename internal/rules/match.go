@@ -0,0 +1,273 @@
+package rules
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRules per code, each is evaluated on every scan
+const MaxRules = 20
+
+const clockLayout = "15:04"
+
+var (
+	countryRe  = regexp.MustCompile(`^[A-Z]{2}$`)
+	languageRe = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+)
+
+// Values accepted in conditions
+var (
+	devices  = set("mobile", "tablet", "desktop", "bot")
+	systems  = set("ios", "android", "windows", "macos", "linux", "chromeos")
+	browsers = set("chrome", "safari", "firefox", "edge", "opera", "samsung")
+	weekdays = map[string]time.Weekday{
+		"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+		"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	}
+)
+
+func set(values ...string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
+
+// Match returns the destination of the first rule the visitor matches
+func (rs *RuleSet) Match(v Visitor) (string, bool) {
+	if rs == nil || len(rs.Rules) == 0 {
+		return "", false
+	}
+	loc, err := time.LoadLocation(rs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := v.Time.In(loc)
+	os, browser := osName(v.OS), browserName(v.Browser)
+
+	for _, r := range rs.Rules {
+		c := r.Conditions
+		if anyOf(c.Devices, strings.ToLower(v.DeviceType)) &&
+			anyOf(c.OS, os) &&
+			anyOf(c.Browsers, browser) &&
+			c.matchLanguage(v.Language) &&
+			anyOf(c.Countries, strings.ToUpper(v.Country)) &&
+			c.matchDay(local.Weekday()) &&
+			c.matchTime(local) {
+			return r.Destination, true
+		}
+	}
+	return "", false
+}
+
+// anyOf matches an unset condition, or one listing value
+func anyOf(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Conditions) matchLanguage(tag string) bool {
+	if len(c.Languages) == 0 {
+		return true
+	}
+	tag = strings.ToLower(tag)
+	for _, l := range c.Languages {
+		if tag == l || strings.HasPrefix(tag, l+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Conditions) matchDay(day time.Weekday) bool {
+	if len(c.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range c.Weekdays {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Conditions) matchTime(t time.Time) bool {
+	if c.TimeFrom == "" {
+		return true
+	}
+	from, _ := minuteOfDay(c.TimeFrom)
+	to, _ := minuteOfDay(c.TimeTo)
+	now := t.Hour()*60 + t.Minute()
+	if from < to {
+		return now >= from && now < to
+	}
+	// e.g. 22:00 to 02:00
+	return now >= from || now < to
+}
+
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse(clockLayout, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// osName maps the useragent OS to a condition value
+func osName(os string) string {
+	switch os {
+	case "iOS":
+		return "ios"
+	case "Android":
+		return "android"
+	case "Windows", "Windows NT", "Windows Phone", "Windows Phone OS":
+		return "windows"
+	case "macOS":
+		return "macos"
+	case "Linux":
+		return "linux"
+	case "ChromeOS", "CrOS":
+		return "chromeos"
+	}
+	return strings.ToLower(os)
+}
+
+// browserName maps the useragent browser to a condition value
+func browserName(name string) string {
+	switch name {
+	case "Chrome", "Headless Chrome":
+		return "chrome"
+	case "Safari", "Mobile Safari":
+		return "safari"
+	case "Opera", "Opera Mini", "Opera Touch":
+		return "opera"
+	case "Samsung Browser":
+		return "samsung"
+	}
+	return strings.ToLower(name)
+}
+
+// PreferredLanguage is the tag of an Accept-Language header the visitor
+// prefers most, e.g. "de-CH" of "de-CH,de;q=0.9,en;q=0.8"
+func PreferredLanguage(header string) string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if tag != "" && tag != "*" && q > 0 {
+			langs = append(langs, lang{tag, q})
+		}
+	}
+	if len(langs) == 0 {
+		return ""
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	return langs[0].tag
+}
+
+// normalize validates the rules and lower-cases (or upper-cases) the
+// condition values, so matching is plain comparison
+func normalize(rs []Rule) ([]Rule, error) {
+	if len(rs) > MaxRules {
+		return nil, fmt.Errorf("%w: at most %d rules", ErrInvalidRules, MaxRules)
+	}
+	out := make([]Rule, len(rs))
+	for i, r := range rs {
+		n, err := r.normalized()
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidRules, i+1, err)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+func (r Rule) normalized() (Rule, error) {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Destination = strings.TrimSpace(r.Destination)
+	if u, err := url.Parse(r.Destination); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return r, fmt.Errorf("destination must be an http(s) URL")
+	}
+
+	c := &r.Conditions
+	var err error
+	if c.Devices, err = values(c.Devices, strings.ToLower, func(v string) bool { return devices[v] }); err != nil {
+		return r, fmt.Errorf("device %v, use mobile, tablet, desktop or bot", err)
+	}
+	if c.OS, err = values(c.OS, strings.ToLower, func(v string) bool { return systems[v] }); err != nil {
+		return r, fmt.Errorf("os %v, use ios, android, windows, macos, linux or chromeos", err)
+	}
+	if c.Browsers, err = values(c.Browsers, strings.ToLower, func(v string) bool { return browsers[v] }); err != nil {
+		return r, fmt.Errorf("browser %v, use chrome, safari, firefox, edge, opera or samsung", err)
+	}
+	if c.Languages, err = values(c.Languages, strings.ToLower, languageRe.MatchString); err != nil {
+		return r, fmt.Errorf("language %v, use a tag like en or pt-br", err)
+	}
+	if c.Countries, err = values(c.Countries, strings.ToUpper, countryRe.MatchString); err != nil {
+		return r, fmt.Errorf("country %v, use an ISO 3166 code like IN", err)
+	}
+	if c.Weekdays, err = values(c.Weekdays, weekday, func(v string) bool { _, ok := weekdays[v]; return ok }); err != nil {
+		return r, fmt.Errorf("weekday %v, use mon to sun", err)
+	}
+
+	c.TimeFrom, c.TimeTo = strings.TrimSpace(c.TimeFrom), strings.TrimSpace(c.TimeTo)
+	if c.TimeFrom != "" || c.TimeTo != "" {
+		from, errFrom := minuteOfDay(c.TimeFrom)
+		to, errTo := minuteOfDay(c.TimeTo)
+		switch {
+		case errFrom != nil || errTo != nil:
+			return r, fmt.Errorf("time_from and time_to are both needed, like 16:00")
+		case from == to:
+			return r, fmt.Errorf("time_from and time_to are the same")
+		}
+	}
+
+	if len(c.Devices)+len(c.OS)+len(c.Browsers)+len(c.Languages)+len(c.Countries)+len(c.Weekdays) == 0 && c.TimeFrom == "" {
+		return r, fmt.Errorf("a rule needs a condition, the code's target_url is what matches always")
+	}
+	return r, nil
+}
+
+// values normalizes a condition's list and checks every value
+func values(in []string, norm func(string) string, ok func(string) bool) ([]string, error) {
+	var out []string
+	for _, v := range in {
+		v = norm(strings.TrimSpace(v))
+		if !ok(v) {
+			return nil, fmt.Errorf("%q is unknown", v)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// weekday accepts full and short day names, any case
+func weekday(s string) string {
+	s = strings.ToLower(s)
+	if len(s) > 3 {
+		s = s[:3]
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package rules
+
+import "time"
+
+// RuleSet is the ordered redirect rules of a dynamic code: the first rule
+// whose conditions all match picks the destination, the code's
+// target_url is the fallback.
+type RuleSet struct {
+	QRID      string    `json:"qr_id"`
+	Timezone  string    `json:"timezone"` // IANA name weekdays and times are local to
+	Rules     []Rule    `json:"rules"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Rule struct {
+	Name        string     `json:"name"` // e.g. "Lunch menu", shown in the editor
+	Conditions  Conditions `json:"conditions"`
+	Destination string     `json:"destination"`
+}
+
+// Conditions of a rule. Every one that is set has to match, a list
+// matches any of its values.
+type Conditions struct {
+	Devices   []string `json:"devices,omitempty"`   // mobile, tablet, desktop, bot
+	OS        []string `json:"os,omitempty"`        // ios, android, windows, macos, linux, chromeos
+	Browsers  []string `json:"browsers,omitempty"`  // chrome, safari, firefox, edge, opera, samsung
+	Languages []string `json:"languages,omitempty"` // preferred Accept-Language, "en" also matches en-US
+	Countries []string `json:"countries,omitempty"` // ISO 3166 alpha-2, e.g. IN
+	Weekdays  []string `json:"weekdays,omitempty"`  // mon, tue, wed, thu, fri, sat, sun
+	TimeFrom  string   `json:"time_from,omitempty"` // 15:04, inclusive
+	TimeTo    string   `json:"time_to,omitempty"`   // exclusive; before time_from the window spans midnight
+}
+
+// SetRulesRequest is the body of PUT /api/qr/rules/:id. An empty rule
+// list removes the rules.
+type SetRulesRequest struct {
+	Timezone string `json:"timezone"` // defaults to the timezone of the user's settings
+	Rules    []Rule `json:"rules"`
+}
+
+// Visitor is the scan rules are matched against
+type Visitor struct {
+	DeviceType string    // Mobile, Tablet, Desktop or Bot, as scan events record it
+	OS         string    // as mileusna/useragent names it, e.g. "iOS"
+	Browser    string    // e.g. "Chrome", "Mobile Safari"
+	Language   string    // most preferred Accept-Language tag
+	Country    string    // ISO 3166 alpha-2, empty when unknown
+	Time       time.Time // of the scan
+}
@@ -0,0 +1,61 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	// GetRules returns nil when the code has no rules
+	GetRules(ctx context.Context, qrID string) (*RuleSet, error)
+	SaveRules(ctx context.Context, rs *RuleSet) error
+	DeleteRules(ctx context.Context, qrID string) error
+}
+
+type repository struct {
+	pg *pgxpool.Pool
+}
+
+func NewRepository(pg *pgxpool.Pool) Repository {
+	return &repository{pg}
+}
+
+func (r *repository) GetRules(ctx context.Context, qrID string) (*RuleSet, error) {
+	rs := RuleSet{QRID: qrID}
+	var raw []byte
+	err := r.pg.QueryRow(ctx,
+		`SELECT timezone, rules, updated_at FROM qr_redirect_rules WHERE qr_id = $1`,
+		qrID).Scan(&rs.Timezone, &raw, &rs.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &rs.Rules); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+func (r *repository) SaveRules(ctx context.Context, rs *RuleSet) error {
+	raw, err := json.Marshal(rs.Rules)
+	if err != nil {
+		return err
+	}
+	_, err = r.pg.Exec(ctx,
+		`INSERT INTO qr_redirect_rules (qr_id, timezone, rules, updated_at)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (qr_id) DO UPDATE SET timezone = EXCLUDED.timezone, rules = EXCLUDED.rules, updated_at = EXCLUDED.updated_at`,
+		rs.QRID, rs.Timezone, raw, rs.UpdatedAt)
+	return err
+}
+
+func (r *repository) DeleteRules(ctx context.Context, qrID string) error {
+	_, err := r.pg.Exec(ctx, `DELETE FROM qr_redirect_rules WHERE qr_id = $1`, qrID)
+	return err
+}
@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"qr-saas/internal/qr"
+	"qr-saas/internal/settings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Service interface {
+	// GetRules returns the code's rules, an empty set when it has none
+	GetRules(ctx context.Context, userID, qrID string) (*RuleSet, error)
+	// SetRules replaces the code's rules, in order
+	SetRules(ctx context.Context, userID, qrID string, req SetRulesRequest) (*RuleSet, error)
+}
+
+var (
+	ErrQRNotFound   = errors.New("qr code not found")
+	ErrNotDynamic   = errors.New("only dynamic codes redirect, static ones can't have rules")
+	ErrInvalidRules = errors.New("invalid rules")
+)
+
+type service struct {
+	repo     Repository
+	qrRepo   qr.Repository
+	settings settings.Service
+}
+
+func NewService(repo Repository, qrRepo qr.Repository, settingsSvc settings.Service) Service {
+	return &service{repo: repo, qrRepo: qrRepo, settings: settingsSvc}
+}
+
+func (s *service) GetRules(ctx context.Context, userID, qrID string) (*RuleSet, error) {
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rs, err := s.repo.GetRules(ctx, code.ID)
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		rs = &RuleSet{QRID: code.ID, Timezone: s.timezone(ctx, userID), Rules: []Rule{}}
+	}
+	return rs, nil
+}
+
+func (s *service) SetRules(ctx context.Context, userID, qrID string, req SetRulesRequest) (*RuleSet, error) {
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !qr.IsDynamic(code.QRType) {
+		return nil, ErrNotDynamic
+	}
+
+	rules, err := normalize(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+	tz := strings.TrimSpace(req.Timezone)
+	if tz == "" {
+		tz = s.timezone(ctx, userID)
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidRules, tz)
+	}
+
+	rs := &RuleSet{QRID: code.ID, Timezone: tz, Rules: rules, UpdatedAt: time.Now().UTC()}
+	if len(rules) == 0 {
+		rs.Rules = []Rule{}
+		return rs, s.repo.DeleteRules(ctx, code.ID)
+	}
+	if err := s.repo.SaveRules(ctx, rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// timezone is the one of the user's settings, the restaurant's lunch
+// hours are in its own time
+func (s *service) timezone(ctx context.Context, userID string) string {
+	sett, err := s.settings.GetSettings(ctx, userID)
+	if err != nil || sett.Timezone == "" {
+		return "UTC"
+	}
+	if _, err := time.LoadLocation(sett.Timezone); err != nil {
+		return "UTC"
+	}
+	return sett.Timezone
+}
@@ -0,0 +1,61 @@
+package scenes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// loadDir reads every <id>.json scene definition in dir. A missing
+// directory just means there are no builtin scenes; broken files are
+// logged and skipped so one bad mockup doesn't hide the rest.
+func loadDir(dir string) []Scene {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(paths)
+
+	var out []Scene
+	for _, p := range paths {
+		s, err := loadFile(p)
+		if err != nil {
+			fmt.Printf("⚠️ Skipping scene %s: %v\n", p, err)
+			continue
+		}
+		out = append(out, *s)
+	}
+	return out
+}
+
+func loadFile(path string) (*Scene, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f sceneFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+	if f.Background == "" {
+		return nil, fmt.Errorf("background is required")
+	}
+
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := f.Name
+	if name == "" {
+		name = id
+	}
+	return &Scene{
+		ID:             id,
+		Name:           name,
+		Description:    f.Description,
+		Quad:           f.Quad,
+		Source:         SourceBuiltin,
+		BackgroundPath: filepath.Join(filepath.Dir(path), f.Background),
+	}, nil
+}
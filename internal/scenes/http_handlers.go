@@ -0,0 +1,55 @@
+package scenes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	svc Service
+}
+
+func RegisterRoutes(r *gin.RouterGroup, svc Service) {
+	h := &Handler{svc}
+
+	r.GET("/", h.List)
+	r.GET("/:id", h.GetOne)
+}
+
+// @Summary List mockup scenes
+// @Description Scenes can be passed as ?scene=<id> to /api/qr/{id}/image
+// @Tags Scenes
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} Scene
+// @Router /api/scenes/ [get]
+func (h *Handler) List(c *gin.Context) {
+	out, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load scenes"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// @Summary Get scene
+// @Tags Scenes
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Scene ID"
+// @Success 200 {object} Scene
+// @Router /api/scenes/{id} [get]
+func (h *Handler) GetOne(c *gin.Context) {
+	s, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,33 @@
+package scenes
+
+import (
+	"time"
+
+	"qr-saas/internal/qr/render"
+)
+
+// Scene is a mockup background a QR can be composited onto
+type Scene struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Quad        render.Quad `json:"quad"`   // where the QR lands: top-left, top-right, bottom-right, bottom-left (px)
+	Source      string      `json:"source"` // "builtin" (assets) or "db"
+	CreatedAt   *time.Time  `json:"created_at,omitempty"`
+
+	BackgroundPath string `json:"-"` // local file, never exposed
+}
+
+const (
+	SourceBuiltin = "builtin"
+	SourceDB      = "db"
+)
+
+// sceneFile is the on-disk definition under the scenes directory,
+// e.g. assets/scenes/table_tent.json
+type sceneFile struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Background  string      `json:"background"` // relative to the json file
+	Quad        render.Quad `json:"quad"`
+}
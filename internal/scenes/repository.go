@@ -0,0 +1,71 @@
+package scenes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	List(ctx context.Context) ([]Scene, error)
+	GetByID(ctx context.Context, id string) (*Scene, error)
+}
+
+type repository struct {
+	pg *pgxpool.Pool
+}
+
+func NewRepository(pg *pgxpool.Pool) Repository {
+	return &repository{pg}
+}
+
+func scanScene(row pgx.Row) (*Scene, error) {
+	var s Scene
+	var quadBytes []byte
+
+	err := row.Scan(&s.ID, &s.Name, &s.Description, &s.BackgroundPath, &quadBytes, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(quadBytes, &s.Quad); err != nil {
+		return nil, err
+	}
+	s.Source = SourceDB
+	return &s, nil
+}
+
+func (r *repository) List(ctx context.Context) ([]Scene, error) {
+	rows, err := r.pg.Query(ctx,
+		`SELECT id, name, description, background_path, quad, created_at
+         FROM scenes ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Scene
+	for rows.Next() {
+		s, err := scanScene(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func (r *repository) GetByID(ctx context.Context, id string) (*Scene, error) {
+	row := r.pg.QueryRow(ctx,
+		`SELECT id, name, description, background_path, quad, created_at
+         FROM scenes WHERE id=$1`,
+		id)
+
+	s, err := scanScene(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
@@ -0,0 +1,66 @@
+package scenes
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+var ErrNotFound = errors.New("scene not found")
+
+type Service interface {
+	List(ctx context.Context) ([]Scene, error)
+	Get(ctx context.Context, id string) (*Scene, error)
+}
+
+type service struct {
+	repo Repository
+	dir  string
+}
+
+// NewService serves scenes from the database and from the json
+// definitions in dir. The directory is re-read on every call so new
+// mockups show up without a restart; a DB scene wins over a file with
+// the same id.
+func NewService(repo Repository, dir string) Service {
+	return &service{repo: repo, dir: dir}
+}
+
+func (s *service) List(ctx context.Context) ([]Scene, error) {
+	stored, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]Scene{}
+	for _, sc := range loadDir(s.dir) {
+		byID[sc.ID] = sc
+	}
+	for _, sc := range stored {
+		byID[sc.ID] = sc
+	}
+
+	out := make([]Scene, 0, len(byID))
+	for _, sc := range byID {
+		out = append(out, sc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *service) Get(ctx context.Context, id string) (*Scene, error) {
+	sc, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sc != nil {
+		return sc, nil
+	}
+
+	for _, f := range loadDir(s.dir) {
+		if f.ID == id {
+			return &f, nil
+		}
+	}
+	return nil, ErrNotFound
+}
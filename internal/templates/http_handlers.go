@@ -1,8 +1,11 @@
 package templates
 
 import (
+	"errors"
 	"net/http"
 
+	"qr-saas/internal/assets"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -69,6 +72,10 @@ func (h *Handler) Create(c *gin.Context) {
 	}
 
 	tpl, err := h.svc.Create(c.Request.Context(), userID, req)
+	if isAssetError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid logo: " + err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
 		return
@@ -121,6 +128,10 @@ func (h *Handler) Update(c *gin.Context) {
 	}
 
 	t, err := h.svc.Update(c.Request.Context(), userID, id, req)
+	if isAssetError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid logo: " + err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
 		return
@@ -150,3 +161,11 @@ func (h *Handler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
+
+// isAssetError reports an inline logo that couldn't be moved into the
+// asset store because of the image itself
+func isAssetError(err error) bool {
+	return errors.Is(err, assets.ErrTooLarge) ||
+		errors.Is(err, assets.ErrUnsupportedType) ||
+		errors.Is(err, assets.ErrTooManyPixels)
+}
@@ -33,3 +33,10 @@ type TemplateInstance struct {
 	Data       map[string]interface{} `json:"data"`
 	CreatedAt  time.Time              `json:"created_at"`
 }
+
+// Download is a file offered on a public page, e.g. the .vcf of a contact
+type Download struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
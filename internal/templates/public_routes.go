@@ -1,6 +1,8 @@
 package templates
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -19,4 +21,20 @@ func RegisterPublicRoutes(r *gin.Engine, svc Service) {
 		c.Header("Content-Type", "text/html")
 		c.String(http.StatusOK, html)
 	})
+
+	// The file behind a page, e.g. "Save contact" on a contact page
+	r.GET("/t/:url_id/download", func(c *gin.Context) {
+		d, err := svc.RenderPublicDownload(c.Request.Context(), c.Param("url_id"))
+		if errors.Is(err, ErrInstanceNotFound) || errors.Is(err, ErrNoDownload) {
+			c.String(http.StatusNotFound, "Not found")
+			return
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Download failed")
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, d.Name))
+		c.Data(http.StatusOK, d.ContentType, d.Data)
+	})
 }
@@ -3,16 +3,67 @@ package templates
 import (
 	"bytes"
 	"html/template"
+	"strings"
+
+	"qr-saas/internal/qrtypes"
 )
 
+// vcardPage is what views/vcard.html renders
+type vcardPage struct {
+	qrtypes.VCardData
+	Phones   []pageLink
+	Emails   []pageLink
+	Download string
+}
+
+type pageLink struct {
+	Type  string
+	Value string
+	Href  template.URL
+}
+
 func RenderVCard(t *Template, inst *TemplateInstance) (string, error) {
 	tpl, err := template.ParseFiles("internal/templates/views/vcard.html")
 	if err != nil {
 		return "", err
 	}
 
+	var card qrtypes.VCardData
+	if err := decodeData(inst.Data, &card); err != nil {
+		return "", err
+	}
+	// Older pages may not pass today's validation, show them as stored
+	if n, err := card.Normalized(); err == nil {
+		card = n
+	} else {
+		if card.Phone != "" {
+			card.Phones = append([]qrtypes.TypedValue{{Type: "cell", Value: card.Phone}}, card.Phones...)
+		}
+		if card.Email != "" {
+			card.Emails = append([]qrtypes.TypedValue{{Type: "work", Value: card.Email}}, card.Emails...)
+		}
+	}
+
+	page := vcardPage{VCardData: card, Download: "/t/" + inst.URLID + "/download"}
+	for _, p := range card.Phones {
+		link := pageLink{Type: p.Type, Value: p.Value}
+		// tel: isn't on html/template's safe list, only checked numbers
+		// (stored ones may predate validation) become links
+		if qrtypes.ValidPhone(p.Value) {
+			link.Href = template.URL("tel:" + strings.NewReplacer(" ", "", "(", "", ")", "", "/", "").Replace(p.Value))
+		}
+		page.Phones = append(page.Phones, link)
+	}
+	for _, e := range card.Emails {
+		link := pageLink{Type: e.Type, Value: e.Value}
+		if qrtypes.ValidEmail(e.Value) {
+			link.Href = template.URL("mailto:" + e.Value)
+		}
+		page.Emails = append(page.Emails, link)
+	}
+
 	var out bytes.Buffer
-	if err := tpl.Execute(&out, inst.Data); err != nil {
+	if err := tpl.Execute(&out, page); err != nil {
 		return "", err
 	}
 
@@ -27,6 +78,33 @@ func RenderSocial(t *Template, inst *TemplateInstance) (string, error) {
 	return "<h1>Social Template</h1>", nil
 }
 
+// eventPage is what views/event.html renders
+type eventPage struct {
+	qrtypes.EventData
+	When     string
+	Download string
+}
+
 func RenderEvent(t *Template, inst *TemplateInstance) (string, error) {
-	return "<h1>Event Template</h1>", nil
+	tpl, err := template.ParseFiles("internal/templates/views/event.html")
+	if err != nil {
+		return "", err
+	}
+
+	var ev qrtypes.EventData
+	if err := decodeData(inst.Data, &ev); err != nil {
+		return "", err
+	}
+
+	page := eventPage{EventData: ev, When: ev.Start, Download: "/t/" + inst.URLID + "/download"}
+	if when, err := ev.When(); err == nil {
+		page.When = when
+	}
+
+	var out bytes.Buffer
+	if err := tpl.Execute(&out, page); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
 }
@@ -17,6 +17,8 @@ type Repository interface {
 	Delete(ctx context.Context, id string, userID string) error
 
 	GetInstanceByURL(ctx context.Context, urlID string) (*TemplateInstance, error)
+	CreateInstance(ctx context.Context, inst *TemplateInstance) error
+	UpdateInstance(ctx context.Context, inst *TemplateInstance) error
 	GetTemplateMeta(ctx context.Context, id string) (*Template, error)
 }
 
@@ -169,6 +171,38 @@ func (r *repository) GetInstanceByURL(ctx context.Context, urlID string) (*Templ
 	return &inst, nil
 }
 
+func (r *repository) CreateInstance(ctx context.Context, inst *TemplateInstance) error {
+	dataBytes, err := json.Marshal(inst.Data)
+	if err != nil {
+		return err
+	}
+	_, err = r.pg.Exec(ctx,
+		`INSERT INTO template_data (id, user_id, template_id, data, url_id, created_at)
+         VALUES ($1,$2,$3,$4,$5,$6)`,
+		inst.ID, inst.UserID, inst.TemplateID, dataBytes, inst.URLID, inst.CreatedAt,
+	)
+	return err
+}
+
+func (r *repository) UpdateInstance(ctx context.Context, inst *TemplateInstance) error {
+	dataBytes, err := json.Marshal(inst.Data)
+	if err != nil {
+		return err
+	}
+	cmd, err := r.pg.Exec(ctx,
+		`UPDATE template_data SET template_id=$1, data=$2
+         WHERE url_id=$3 AND user_id=$4`,
+		inst.TemplateID, dataBytes, inst.URLID, inst.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrInstanceNotFound
+	}
+	return nil
+}
+
 func (r *repository) GetTemplateMeta(ctx context.Context, id string) (*Template, error) {
 	row := r.pg.QueryRow(ctx,
 		`SELECT id, user_id, category, name, thumbnail, design_json, created_at 
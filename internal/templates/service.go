@@ -2,8 +2,15 @@ package templates
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
+	"qr-saas/internal/assets"
+	"qr-saas/internal/qr"
+	"qr-saas/internal/qrtypes"
+
 	"github.com/google/uuid"
 )
 
@@ -17,17 +24,35 @@ type Service interface {
 
 	// Add this ↓↓↓
 	RenderPublicPage(ctx context.Context, urlID string) (string, error)
+	// RenderPublicDownload is the file behind a public page, the .vcf of a
+	// contact page or the .ics of an event
+	RenderPublicDownload(ctx context.Context, urlID string) (*Download, error)
+
+	// SaveInstance publishes the landing page of a dynamic typed code, see
+	// qrtypes.Pages
+	SaveInstance(ctx context.Context, userID, category, templateID, urlID string, data map[string]interface{}) (string, error)
 }
 
+var (
+	ErrInstanceNotFound = errors.New("page not found")
+	ErrNoDownload       = errors.New("page has no download")
+)
+
 type service struct {
-	repo Repository
+	repo   Repository
+	assets assets.Service
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo}
+func NewService(repo Repository, assetSvc assets.Service) Service {
+	return &service{repo: repo, assets: assetSvc}
 }
 
 func (s *service) Create(ctx context.Context, userID string, req CreateTemplateRequest) (*Template, error) {
+	// Keep base64 logos out of design_json, reference an asset instead
+	if err := s.assets.ExtractInlineLogo(ctx, userID, req.DesignJSON); err != nil {
+		return nil, err
+	}
+
 	t := &Template{
 		ID:         uuid.New().String(),
 		UserID:     &userID,
@@ -74,6 +99,9 @@ func (s *service) Update(ctx context.Context, userID, id string, req UpdateTempl
 		t.Thumbnail = *req.Thumbnail
 	}
 	if req.DesignJSON != nil {
+		if err := s.assets.ExtractInlineLogo(ctx, userID, req.DesignJSON); err != nil {
+			return nil, err
+		}
 		t.DesignJSON = req.DesignJSON
 	}
 
@@ -110,3 +138,118 @@ func (s *service) RenderPublicPage(ctx context.Context, urlID string) (string, e
 		return RenderGeneric(template, instance)
 	}
 }
+
+func (s *service) RenderPublicDownload(ctx context.Context, urlID string) (*Download, error) {
+	instance, err := s.repo.GetInstanceByURL(ctx, urlID)
+	if err != nil {
+		return nil, ErrInstanceNotFound
+	}
+	template, err := s.repo.GetTemplateMeta(ctx, instance.TemplateID)
+	if err != nil || template == nil {
+		return nil, ErrInstanceNotFound
+	}
+
+	switch template.Category {
+	case qrtypes.TypeVCard:
+		var card qrtypes.VCardData
+		if err := decodeData(instance.Data, &card); err != nil {
+			return nil, err
+		}
+		vcf, err := card.VCF()
+		if err != nil {
+			return nil, err
+		}
+		return &Download{Name: fileName(card.FullName, "contact") + ".vcf", ContentType: "text/vcard; charset=utf-8", Data: vcf}, nil
+	case qrtypes.TypeEvent:
+		var ev qrtypes.EventData
+		if err := decodeData(instance.Data, &ev); err != nil {
+			return nil, err
+		}
+		// The page is the event's identity, a second download updates it
+		ics, err := ev.ICS(instance.URLID + "@qr-saas")
+		if err != nil {
+			return nil, err
+		}
+		return &Download{Name: fileName(ev.Title, "event") + ".ics", ContentType: "text/calendar; charset=utf-8", Data: ics}, nil
+	default:
+		return nil, ErrNoDownload
+	}
+}
+
+func (s *service) SaveInstance(ctx context.Context, userID, category, templateID, urlID string, data map[string]interface{}) (string, error) {
+	t, err := s.pageTemplate(ctx, userID, category, templateID)
+	if err != nil {
+		return "", err
+	}
+
+	inst := &TemplateInstance{UserID: userID, TemplateID: t.ID, URLID: urlID, Data: data}
+	if urlID != "" {
+		err := s.repo.UpdateInstance(ctx, inst)
+		if !errors.Is(err, ErrInstanceNotFound) {
+			return urlID, err
+		}
+		// The page is gone, publish a new one
+	}
+
+	inst.ID = uuid.New().String()
+	inst.CreatedAt = time.Now().UTC()
+	if inst.URLID, err = qr.GenerateShortCode(10); err != nil {
+		return "", err
+	}
+	if err := s.repo.CreateInstance(ctx, inst); err != nil {
+		return "", err
+	}
+	return inst.URLID, nil
+}
+
+// pageTemplate is the user's pick of a category's templates, or the first
+// global one
+func (s *service) pageTemplate(ctx context.Context, userID, category, templateID string) (*Template, error) {
+	if templateID == "" {
+		global, err := s.repo.ListGlobal(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range global {
+			if t.Category == category {
+				return &t, nil
+			}
+		}
+		return nil, qrtypes.ErrTemplateNotFound
+	}
+
+	t, err := s.repo.GetByID(ctx, templateID, userID)
+	if err != nil || t == nil || t.Category != category {
+		return nil, qrtypes.ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// decodeData reads instance data into a typed struct
+func decodeData(data map[string]interface{}, v interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// fileName makes a download name out of name, fallback when nothing is left
+func fileName(name, fallback string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	out := strings.TrimSuffix(b.String(), "-")
+	if out == "" {
+		return fallback
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package variants
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	svc Service
+}
+
+func RegisterRoutes(r *gin.RouterGroup, svc Service) {
+	h := &Handler{svc}
+
+	r.GET("/:id", h.GetSplit)
+	r.PUT("/:id", h.SetVariants)
+}
+
+// @Summary Get the A/B variants of a code
+// @Tags A/B Testing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "QR ID"
+// @Success 200 {object} Split
+// @Router /api/qr/variants/{id} [get]
+func (h *Handler) GetSplit(c *gin.Context) {
+	split, err := h.svc.GetSplit(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, split)
+}
+
+// @Summary Split a code's traffic between destinations
+// @Description Replaces the weighted variants of a dynamic code. Each scanner is assigned a variant by weight and keeps it (cookie, or a hash of IP and user agent). Scans record the variant; landing pages report conversions with the pixel /r/{code}/convert. Redirect rules that match take precedence. An empty list ends the test.
+// @Tags A/B Testing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "QR ID"
+// @Param data body SetVariantsRequest true "variants"
+// @Success 200 {object} Split
+// @Router /api/qr/variants/{id} [put]
+func (h *Handler) SetVariants(c *gin.Context) {
+	var req SetVariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	split, err := h.svc.SetVariants(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	if respondError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, split)
+}
+
+// respondError writes the response for err and reports whether there was one
+func respondError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrQRNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidVariants), errors.Is(err, ErrNotDynamic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+package variants
+
+import "time"
+
+// Split is the A/B test of a dynamic code: scanners are assigned one of
+// the variants by weight and keep it on later scans.
+type Split struct {
+	QRID      string    `json:"qr_id"`
+	Variants  []Variant `json:"variants"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Variant struct {
+	ID          string `json:"id"`   // recorded on scans and conversions, e.g. "a"; defaults to a, b, c...
+	Name        string `json:"name"` // e.g. "Summer landing page"
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"` // share of traffic relative to the others, e.g. 80 and 20; 0 pauses the variant
+}
+
+// SetVariantsRequest is the body of PUT /api/qr/variants/:id. An empty
+// list ends the test.
+type SetVariantsRequest struct {
+	Variants []Variant `json:"variants"`
+}
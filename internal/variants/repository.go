@@ -0,0 +1,61 @@
+package variants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	// GetSplit returns nil when the code isn't split
+	GetSplit(ctx context.Context, qrID string) (*Split, error)
+	SaveSplit(ctx context.Context, s *Split) error
+	DeleteSplit(ctx context.Context, qrID string) error
+}
+
+type repository struct {
+	pg *pgxpool.Pool
+}
+
+func NewRepository(pg *pgxpool.Pool) Repository {
+	return &repository{pg}
+}
+
+func (r *repository) GetSplit(ctx context.Context, qrID string) (*Split, error) {
+	s := Split{QRID: qrID}
+	var raw []byte
+	err := r.pg.QueryRow(ctx,
+		`SELECT variants, updated_at FROM qr_variants WHERE qr_id = $1`,
+		qrID).Scan(&raw, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &s.Variants); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *repository) SaveSplit(ctx context.Context, s *Split) error {
+	raw, err := json.Marshal(s.Variants)
+	if err != nil {
+		return err
+	}
+	_, err = r.pg.Exec(ctx,
+		`INSERT INTO qr_variants (qr_id, variants, updated_at)
+         VALUES ($1, $2, $3)
+         ON CONFLICT (qr_id) DO UPDATE SET variants = EXCLUDED.variants, updated_at = EXCLUDED.updated_at`,
+		s.QRID, raw, s.UpdatedAt)
+	return err
+}
+
+func (r *repository) DeleteSplit(ctx context.Context, qrID string) error {
+	_, err := r.pg.Exec(ctx, `DELETE FROM qr_variants WHERE qr_id = $1`, qrID)
+	return err
+}
@@ -0,0 +1,78 @@
+package variants
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"qr-saas/internal/qr"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Service interface {
+	// GetSplit returns the code's variants, none when it isn't split
+	GetSplit(ctx context.Context, userID, qrID string) (*Split, error)
+	// SetVariants replaces the code's variants; scanners keep theirs as
+	// long as it still runs
+	SetVariants(ctx context.Context, userID, qrID string, req SetVariantsRequest) (*Split, error)
+}
+
+var (
+	ErrQRNotFound      = errors.New("qr code not found")
+	ErrNotDynamic      = errors.New("only dynamic codes redirect, static ones can't be split")
+	ErrInvalidVariants = errors.New("invalid variants")
+)
+
+type service struct {
+	repo   Repository
+	qrRepo qr.Repository
+}
+
+func NewService(repo Repository, qrRepo qr.Repository) Service {
+	return &service{repo: repo, qrRepo: qrRepo}
+}
+
+func (s *service) GetSplit(ctx context.Context, userID, qrID string) (*Split, error) {
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	split, err := s.repo.GetSplit(ctx, code.ID)
+	if err != nil {
+		return nil, err
+	}
+	if split == nil {
+		split = &Split{QRID: code.ID, Variants: []Variant{}}
+	}
+	return split, nil
+}
+
+func (s *service) SetVariants(ctx context.Context, userID, qrID string, req SetVariantsRequest) (*Split, error) {
+	code, err := s.qrRepo.GetByID(ctx, qrID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrQRNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !qr.IsDynamic(code.QRType) {
+		return nil, ErrNotDynamic
+	}
+
+	vs, err := normalize(req.Variants)
+	if err != nil {
+		return nil, err
+	}
+	split := &Split{QRID: code.ID, Variants: vs, UpdatedAt: time.Now().UTC()}
+	if len(vs) == 0 {
+		return split, s.repo.DeleteSplit(ctx, code.ID)
+	}
+	if err := s.repo.SaveSplit(ctx, split); err != nil {
+		return nil, err
+	}
+	return split, nil
+}
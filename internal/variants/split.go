@@ -0,0 +1,101 @@
+package variants
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Limits of a split
+const (
+	MinVariants = 2
+	MaxVariants = 10
+	MaxWeight   = 1000
+)
+
+var idRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Assign picks the variant of a scanner: the one it was given before
+// (current, from its cookie) while that still runs, otherwise one drawn
+// by weight from a hash of key, so the same scanner lands on the same
+// variant even without cookies.
+func (s *Split) Assign(current, key string) (Variant, bool) {
+	if s == nil {
+		return Variant{}, false
+	}
+	total := 0
+	for _, v := range s.Variants {
+		if v.ID == current && v.Weight > 0 {
+			return v, true
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return Variant{}, false
+	}
+
+	sum := sha256.Sum256([]byte(s.QRID + "|" + key))
+	n := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, v := range s.Variants {
+		if n < v.Weight {
+			return v, true
+		}
+		n -= v.Weight
+	}
+	return Variant{}, false
+}
+
+// Find returns the variant with id
+func (s *Split) Find(id string) (Variant, bool) {
+	if s != nil {
+		for _, v := range s.Variants {
+			if v.ID == id {
+				return v, true
+			}
+		}
+	}
+	return Variant{}, false
+}
+
+// normalize validates the variants and fills in missing ids
+func normalize(in []Variant) ([]Variant, error) {
+	if len(in) == 0 {
+		return []Variant{}, nil
+	}
+	if len(in) < MinVariants || len(in) > MaxVariants {
+		return nil, fmt.Errorf("%w: a split has %d to %d variants", ErrInvalidVariants, MinVariants, MaxVariants)
+	}
+
+	out := make([]Variant, len(in))
+	seen := map[string]bool{}
+	total := 0
+	for i, v := range in {
+		v.ID = strings.ToLower(strings.TrimSpace(v.ID))
+		if v.ID == "" {
+			v.ID = string(rune('a' + i))
+		}
+		v.Name = strings.TrimSpace(v.Name)
+		v.Destination = strings.TrimSpace(v.Destination)
+
+		switch u, err := url.Parse(v.Destination); {
+		case !idRe.MatchString(v.ID):
+			return nil, fmt.Errorf("%w: variant id %q, use up to 32 letters, digits, - or _", ErrInvalidVariants, v.ID)
+		case seen[v.ID]:
+			return nil, fmt.Errorf("%w: variant id %q is used twice", ErrInvalidVariants, v.ID)
+		case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+			return nil, fmt.Errorf("%w: variant %s: destination must be an http(s) URL", ErrInvalidVariants, v.ID)
+		case v.Weight < 0 || v.Weight > MaxWeight:
+			return nil, fmt.Errorf("%w: variant %s: weight must be 0 to %d", ErrInvalidVariants, v.ID, MaxWeight)
+		}
+		seen[v.ID] = true
+		total += v.Weight
+		out[i] = v
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: at least one variant needs a weight", ErrInvalidVariants)
+	}
+	return out, nil
+}
@@ -0,0 +1,11 @@
+-- Mockup scenes for composited QR previews. Builtin scenes also ship as
+-- json files under assets/scenes; a row with the same id overrides one.
+CREATE TABLE scenes (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    background_path TEXT NOT NULL,
+    -- [{"x":..,"y":..} x4]: top-left, top-right, bottom-right, bottom-left in background pixels
+    quad JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
@@ -0,0 +1,17 @@
+-- Uploaded images (logos). Bytes live in the asset store (local disk or
+-- S3) under sha256/<aa>/<bb>/<sha256>; designs reference them by id as
+-- "logoAssetId" instead of inlining base64.
+CREATE TABLE assets (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    filename TEXT NOT NULL DEFAULT '',
+    mime TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX assets_user_id_idx ON assets (user_id, created_at DESC);
+CREATE UNIQUE INDEX assets_user_sha256_idx ON assets (user_id, sha256);
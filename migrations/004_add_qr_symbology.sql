@@ -0,0 +1,3 @@
+-- Barcode symbology per code. Linear symbologies (ean13, upca, code128)
+-- are static only; every 2D one can carry a dynamic short link.
+ALTER TABLE qr_codes ADD COLUMN symbology TEXT NOT NULL DEFAULT 'qr';
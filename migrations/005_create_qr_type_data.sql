@@ -0,0 +1,8 @@
+-- Structured data behind typed static codes (WiFi, vCard, ...). The
+-- encoded payload is what the code carries, in qr_codes.target_url.
+CREATE TABLE qr_type_data (
+    qr_id UUID PRIMARY KEY,
+    type TEXT NOT NULL,
+    data JSONB NOT NULL,
+    updated_at TIMESTAMP DEFAULT now()
+);
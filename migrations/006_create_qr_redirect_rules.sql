@@ -0,0 +1,9 @@
+-- Ordered conditional redirect rules of a dynamic code (device, OS,
+-- language, country, weekday, time window). The first matching rule's
+-- destination wins, qr_codes.target_url is the fallback.
+CREATE TABLE qr_redirect_rules (
+    qr_id UUID PRIMARY KEY,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    rules JSONB NOT NULL,
+    updated_at TIMESTAMP DEFAULT now()
+);
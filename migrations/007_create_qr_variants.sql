@@ -0,0 +1,20 @@
+-- A/B tests: weighted destinations of a dynamic code. Scans record the
+-- variant they were sent to, landing pages report conversions.
+CREATE TABLE qr_variants (
+    qr_id UUID PRIMARY KEY,
+    variants JSONB NOT NULL,
+    updated_at TIMESTAMP DEFAULT now()
+);
+
+ALTER TABLE scan_events ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE conversion_events (
+    id UUID PRIMARY KEY,
+    qr_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    variant TEXT NOT NULL DEFAULT '',
+    visitor TEXT NOT NULL, -- hash of ip and user agent, conversions count once per visitor
+    converted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX conversion_events_qr_idx ON conversion_events (qr_id, converted_at);
@@ -0,0 +1,7 @@
+-- Validity window and scan cap of a dynamic code. Outside of them scans
+-- get the code's fallback (an alternate URL or an unavailable page).
+ALTER TABLE qr_codes ADD COLUMN valid_from TIMESTAMPTZ;
+ALTER TABLE qr_codes ADD COLUMN valid_until TIMESTAMPTZ;
+ALTER TABLE qr_codes ADD COLUMN max_scans INT;
+ALTER TABLE qr_codes ADD COLUMN scan_count INT NOT NULL DEFAULT 0;
+ALTER TABLE qr_codes ADD COLUMN fallbacks JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+-- Password-protected dynamic codes: the redirect asks for the password
+-- (bcrypt hash here) before sending scanners on.
+ALTER TABLE qr_codes ADD COLUMN password_hash TEXT;